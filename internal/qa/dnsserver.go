@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package qa
+
+import (
+	"net"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore/dnscoretest"
+	"github.com/rbmk-project/x/netsim"
+)
+
+// ServeDNSTruncatingOverUDP returns a ScenarioEditor that attaches a
+// DNS server using the given addresses. The server answers queries using
+// the scenario's DNS database. However, responses sent over UDP are
+// truncated: they have the TC bit set and do not contain any record. The
+// full responses are only available over TCP, such that clients need to
+// retry using TCP (e.g., `rbmk dig --tcp-fallback`) to obtain them.
+func ServeDNSTruncatingOverUDP(addrs ...string) ScenarioEditor {
+	return func(scenario *netsim.Scenario) *netsim.Scenario {
+		handler := scenario.DNSHandler()
+		truncating := dnscoretest.HandlerFunc(func(rw dnscoretest.ResponseWriter, rawQuery []byte) {
+			handler.Handle(&truncatingResponseWriter{rw}, rawQuery)
+		})
+		scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+			Addresses:         addrs,
+			DNSOverUDPHandler: truncating,
+			DNSOverTCPHandler: handler,
+		}))
+		return scenario
+	}
+}
+
+// truncatingResponseWriter is a [dnscoretest.ResponseWriter] that
+// truncates the responses before writing them.
+type truncatingResponseWriter struct {
+	rw dnscoretest.ResponseWriter
+}
+
+// Write implements [dnscoretest.ResponseWriter].
+func (w *truncatingResponseWriter) Write(rawResp []byte) (int, error) {
+	resp := &dns.Msg{}
+	if err := resp.Unpack(rawResp); err != nil {
+		return 0, err
+	}
+	resp.Truncated = true
+	resp.Answer = nil
+	resp.Ns = nil
+	resp.Extra = nil
+	rawTrunc, err := resp.Pack()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.rw.Write(rawTrunc); err != nil {
+		return 0, err
+	}
+	return len(rawResp), nil
+}
+
+// ServeDNSSinkhole returns a ScenarioEditor that attaches a DNS server
+// using the given addresses, modeling an open resolver that answers every
+// A query with the given sinkhole address, regardless of the name, and
+// answers any other query with an empty response.
+func ServeDNSSinkhole(sinkhole string, addrs ...string) ScenarioEditor {
+	return func(scenario *netsim.Scenario) *netsim.Scenario {
+		handler := dnscoretest.HandlerFunc(func(rw dnscoretest.ResponseWriter, rawQuery []byte) {
+			query := &dns.Msg{}
+			if err := query.Unpack(rawQuery); err != nil || len(query.Question) != 1 {
+				return
+			}
+			resp := &dns.Msg{}
+			resp.SetReply(query)
+			resp.RecursionAvailable = true
+			if q0 := query.Question[0]; q0.Qtype == dns.TypeA {
+				resp.Answer = append(resp.Answer, &dns.A{
+					Hdr: dns.RR_Header{
+						Name:   q0.Name,
+						Rrtype: dns.TypeA,
+						Class:  dns.ClassINET,
+						Ttl:    3600,
+					},
+					A: net.ParseIP(sinkhole),
+				})
+			}
+			rawResp, err := resp.Pack()
+			if err != nil {
+				return
+			}
+			rw.Write(rawResp)
+		})
+		scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+			Addresses:         addrs,
+			DNSOverUDPHandler: handler,
+			DNSOverTCPHandler: handler,
+		}))
+		return scenario
+	}
+}
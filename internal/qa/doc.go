@@ -16,8 +16,10 @@ expected results to create a complete test case. The [Registry] variable
 contains all the available scenarios.
 
 The [Driver] interface abstracts running scenarios. It is compatible with
-[testing.T] and testify's TestingT, allowing scenarios to be used both in
-tests and standalone QA runs.
+[testing.T], allowing scenarios to be used both in tests and standalone
+QA runs, without linking a testing library into the `rbmk` binary. For
+standalone runs, the [*ReportDriver] records whether each scenario
+passed and writes a human-readable report.
 
 # Architecture
 
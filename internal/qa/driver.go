@@ -5,8 +5,8 @@ package qa
 import "time"
 
 // Driver is the interface for running QA scenarios. It is compatible with
-// [testing.T] and testify's TestingT, allowing scenarios to be used both in
-// automated tests and standalone QA
+// [testing.T], allowing scenarios to be used both in automated tests and
+// standalone QA
 type Driver interface {
 	// Deadline returns the suite deadline or false if there is no deadline.
 	Deadline() (time.Time, bool)
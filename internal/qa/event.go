@@ -6,8 +6,6 @@ import (
 	"net"
 	"strconv"
 	"time"
-
-	"github.com/stretchr/testify/require"
 )
 
 // MatchPattern indicates what kinds of messages an
@@ -43,6 +41,18 @@ type ExpectedEvent struct {
 	// this field is ignored and the Pattern is used instead.
 	Msg string
 
+	// Protocol is the optional expected network protocol (e.g., "tcp",
+	// "udp"). If empty, we do not check the event protocol.
+	Protocol string
+
+	// TLSSkipVerify optionally requires the event to indicate that
+	// the TLS verification was skipped. If false, we do not check.
+	TLSSkipVerify bool
+
+	// Bogons optionally requires the event to report the given number
+	// of responses containing bogon addresses. If zero, we do not check.
+	Bogons int
+
 	// When Pattern is non-zero, this [*ExpectedEvent] acts like a
 	// wildcard that consumes all matching events until the next
 	// non-Pattern expectation is found.
@@ -151,6 +161,13 @@ type Event struct {
 
 	// TLSPeerCerts is the list of TLS peer certificates.
 	TLSPeerCerts [][]byte `json:"tlsPeerCerts,omitempty"`
+
+	//
+	// DNS sweep summary fields
+	//
+
+	// Bogons is the number of responses containing bogon addresses.
+	Bogons int `json:"bogons,omitempty"`
 }
 
 // VerifyReadWriteClose checks that the current [*Event] matches
@@ -197,7 +214,7 @@ func (ev *Event) VerifyReadWriteClose(t Driver) {
 		ev.verifyIOBytesCountZero(t)
 
 	default:
-		require.Fail(t, "unexpected message %q", ev.Msg)
+		requireTrue(t, false, "unexpected message %q", ev.Msg)
 	}
 
 	ev.verifyDNSRawQueryEmpty(t)
@@ -215,109 +232,126 @@ func (ev *Event) VerifyReadWriteClose(t Driver) {
 }
 
 func (ev *Event) verifyStartEventTime(t Driver) {
-	require.False(t, ev.T.IsZero(), "expected non-zero t field")
-	require.True(t, ev.T0.IsZero(), "expected zero t0 field")
+	requireTrue(t, !ev.T.IsZero(), "expected non-zero t field")
+	requireTrue(t, ev.T0.IsZero(), "expected zero t0 field")
 }
 
 func (ev *Event) verifyDoneEventTime(t Driver) {
-	require.False(t, ev.T.IsZero(), "expected non-zero t field")
-	require.False(t, ev.T0.IsZero(), "expected non-zero t0 field")
-	require.False(t, ev.T.Before(ev.T0), "expected t >= t0")
+	requireTrue(t, !ev.T.IsZero(), "expected non-zero t field")
+	requireTrue(t, !ev.T0.IsZero(), "expected non-zero t0 field")
+	requireTrue(t, !ev.T.Before(ev.T0), "expected t >= t0")
 }
 
 func (ev *Event) verifyProtocol(t Driver) {
-	require.True(t,
+	requireTrue(t,
 		ev.Protocol == "tcp" || ev.Protocol == "udp",
 		"expected protocol to be tcp or udp")
 }
 
 func (ev *Event) verifyEndpoint(t Driver, epnt string) {
 	addr, port, err := net.SplitHostPort(epnt)
-	require.NoError(t, err, "expected valid endpoint")
-	require.True(t, net.ParseIP(addr) != nil, "expected valid IP address")
+	requireNoError(t, err, "expected valid endpoint")
+	requireTrue(t, net.ParseIP(addr) != nil, "expected valid IP address")
 	pnum, err := strconv.Atoi(port)
-	require.NoError(t, err, "expected valid port number")
-	require.True(t, pnum >= 1 && pnum <= 65535, "expected valid port number")
+	requireNoError(t, err, "expected valid port number")
+	requireTrue(t, pnum >= 1 && pnum <= 65535, "expected valid port number")
 }
 
 func (ev *Event) verifyErrEmpty(t Driver) {
-	require.Empty(t, ev.Err, "expected empty error field")
+	requireTrue(t, len(ev.Err) <= 0, "expected empty error field")
 }
 
 func (ev *Event) verifyErrClassEmpty(t Driver) {
-	require.Empty(t, ev.ErrClass, "expected empty errClass field")
+	requireTrue(t, len(ev.ErrClass) <= 0, "expected empty errClass field")
 }
 
 func (ev *Event) verifyIOBufferSizePositive(t Driver) {
-	require.True(t, ev.IOBufferSize > 0, "expected positive ioBufferSize field")
+	requireTrue(t, ev.IOBufferSize > 0, "expected positive ioBufferSize field")
 }
 
 func (ev *Event) verifyIOBufferSizeZero(t Driver) {
-	require.Zero(t, ev.IOBufferSize, "expected zero ioBufferSize field")
+	requireTrue(t, ev.IOBufferSize == 0, "expected zero ioBufferSize field")
 }
 
 func (ev *Event) verifyIOBytesCountZero(t Driver) {
-	require.Zero(t, ev.IOBytesCount, "expected zero ioBytesCount field")
+	requireTrue(t, ev.IOBytesCount == 0, "expected zero ioBytesCount field")
 }
 
 func (ev *Event) verifyIOBytesCountOrErr(t Driver) {
-	require.True(t, ev.IOBytesCount > 0 || ev.Err != "", "expected ioBytesCount > 0 or err != \"\"")
+	requireTrue(t, ev.IOBytesCount > 0 || ev.Err != "", "expected ioBytesCount > 0 or err != \"\"")
 }
 
 func (ev *Event) verifyIOBytesCountOrErrClass(t Driver) {
-	require.True(t, ev.IOBytesCount > 0 || ev.ErrClass != "", "expected ioBytesCount > 0 or errClass != \"\"")
+	requireTrue(t, ev.IOBytesCount > 0 || ev.ErrClass != "", "expected ioBytesCount > 0 or errClass != \"\"")
 }
 
 func (ev *Event) verifyDNSRawQueryEmpty(t Driver) {
-	require.Empty(t, ev.DNSRawQuery, "expected empty dnsRawQuery field")
+	requireTrue(t, len(ev.DNSRawQuery) <= 0, "expected empty dnsRawQuery field")
 }
 
 func (ev *Event) verifyDNSRawResponseEmpty(t Driver) {
-	require.Empty(t, ev.DNSRawResponse, "expected empty dnsRawResponse field")
+	requireTrue(t, len(ev.DNSRawResponse) <= 0, "expected empty dnsRawResponse field")
 }
 
 func (ev *Event) verifyDNSLookupDomainEmpty(t Driver) {
-	require.Empty(t, ev.DNSLookupDomain, "expected empty dnsLookupDomain field")
+	requireTrue(t, len(ev.DNSLookupDomain) <= 0, "expected empty dnsLookupDomain field")
 }
 
 func (ev *Event) verifyDNSResolverAddrsEmpty(t Driver) {
-	require.Empty(t, ev.DNSResolvedAddrs, "expected empty dnsResolvedAddrs field")
+	requireTrue(t, len(ev.DNSResolvedAddrs) <= 0, "expected empty dnsResolvedAddrs field")
 }
 
 func (ev *Event) verifyServerAddrEmpty(t Driver) {
-	require.Empty(t, ev.ServerAddr, "expected empty serverAddr field")
+	requireTrue(t, len(ev.ServerAddr) <= 0, "expected empty serverAddr field")
 }
 
 func (ev *Event) verifyServerProtocolEmpty(t Driver) {
-	require.Empty(t, ev.ServerProtocol, "expected empty serverProtocol field")
+	requireTrue(t, len(ev.ServerProtocol) <= 0, "expected empty serverProtocol field")
 }
 
 func (ev *Event) verifyTLSServerNameEmpty(t Driver) {
-	require.Empty(t, ev.TLSServerName, "expected empty tlsServerName field")
+	requireTrue(t, len(ev.TLSServerName) <= 0, "expected empty tlsServerName field")
 }
 
 func (ev *Event) verifyTLSSkipVerifyFalse(t Driver) {
-	require.False(t, ev.TLSSkipVerify, "expected false tlsSkipVerify field")
+	requireTrue(t, !ev.TLSSkipVerify, "expected false tlsSkipVerify field")
 }
 
 func (ev *Event) verifyTLSCipherSuiteEmpty(t Driver) {
-	require.Empty(t, ev.TLSCipherSuite, "expected empty tlsCipherSuite field")
+	requireTrue(t, len(ev.TLSCipherSuite) <= 0, "expected empty tlsCipherSuite field")
 }
 
 func (ev *Event) verifyTLSNegotiatedProtoEmpty(t Driver) {
-	require.Empty(t, ev.TLSNegotiatedProto, "expected empty tlsNegotiatedProtocol field")
+	requireTrue(t, len(ev.TLSNegotiatedProto) <= 0, "expected empty tlsNegotiatedProtocol field")
 }
 
 func (ev *Event) verifyTLSVersionEmpty(t Driver) {
-	require.Empty(t, ev.TLSVersion, "expected empty tlsVersion field")
+	requireTrue(t, len(ev.TLSVersion) <= 0, "expected empty tlsVersion field")
 }
 
 func (ev *Event) verifyTLSPeerCertsEmpty(t Driver) {
-	require.Empty(t, ev.TLSPeerCerts, "expected empty tlsPeerCerts field")
+	requireTrue(t, len(ev.TLSPeerCerts) <= 0, "expected empty tlsPeerCerts field")
 }
 
 // VerifyEqual checks whether an event is equal to another.
 func (expect *ExpectedEvent) VerifyEqual(t Driver, got *Event) {
 	// Make sure the messages are equal
-	require.Equal(t, expect.Msg, got.Msg, "expected %q, got %q", expect.Msg, got.Msg)
+	requireTrue(t, expect.Msg == got.Msg, "expected %q, got %q", expect.Msg, got.Msg)
+
+	// Make sure the protocols are equal, if needed
+	if expect.Protocol != "" {
+		requireTrue(t, expect.Protocol == got.Protocol,
+			"expected protocol %q, got %q", expect.Protocol, got.Protocol)
+	}
+
+	// Make sure we skipped the TLS verification, if needed
+	if expect.TLSSkipVerify {
+		requireTrue(t, got.TLSSkipVerify, "expected true tlsSkipVerify field")
+	}
+
+	// Make sure we detected the expected number of bogons, if needed
+	if expect.Bogons != 0 {
+		requireTrue(t, expect.Bogons == got.Bogons,
+			"expected %d bogons, got %d", expect.Bogons, got.Bogons)
+	}
 }
@@ -6,15 +6,22 @@ import (
 	"testing"
 
 	"github.com/rbmk-project/rbmk/internal/qa"
+	"github.com/rbmk-project/rbmk/pkg/cli"
 )
 
+// testRunConfig is the [*qa.RunConfig] to use for testing.
+var testRunConfig = &qa.RunConfig{
+	CacheDir:   "testdata",
+	NewCommand: cli.NewCommand,
+}
+
 func TestQA(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skip test in short mode")
 	}
 	for _, scenario := range qa.Registry {
 		t.Run(scenario.Name, func(t *testing.T) {
-			scenario.VerifyEvents(t, scenario.Run(t))
+			scenario.VerifyEvents(t, scenario.Run(t, testRunConfig))
 		})
 	}
 }
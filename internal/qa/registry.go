@@ -2,6 +2,8 @@
 
 package qa
 
+import "errors"
+
 // Registry is the list of all the available [ScenarioDescriptor].
 var Registry = []ScenarioDescriptor{
 
@@ -66,6 +68,81 @@ var Registry = []ScenarioDescriptor{
 		},
 	},
 
+	{
+		Name: "dnsOverUdpTruncationWithTcpFallback",
+		Editors: []ScenarioEditor{
+			ServeDNSTruncatingOverUDP("9.9.9.9"),
+		},
+		Argv: []string{
+			"rbmk", "dig", "--tcp-fallback", "+noall", "+logs", "@9.9.9.9", "A", "www.example.com",
+		},
+		ExpectedErr: nil,
+		ExpectedSeq: []ExpectedEvent{
+			{Msg: "connectStart", Protocol: "udp"},
+			{Msg: "connectDone", Protocol: "udp"},
+			{Msg: "dnsQuery", Protocol: "udp"},
+			{Pattern: MatchAnyRead | MatchAnyWrite},
+			{Msg: "dnsResponse", Protocol: "udp"},
+			{Pattern: MatchAnyClose},
+			{Msg: "connectStart", Protocol: "tcp"},
+			{Pattern: MatchAnyClose},
+			{Msg: "connectDone", Protocol: "tcp"},
+			{Pattern: MatchAnyClose},
+			{Msg: "dnsQuery", Protocol: "tcp"},
+			{Pattern: MatchAnyRead | MatchAnyWrite | MatchAnyClose},
+			{Msg: "dnsResponse", Protocol: "tcp"},
+			{Pattern: MatchAnyClose},
+		},
+	},
+
+	{
+		Name: "dnsOverUdpSinkholeFailOnBogon",
+		Editors: []ScenarioEditor{
+			ServeDNSSinkhole("127.0.0.1", "10.0.0.53"),
+		},
+		Argv: []string{
+			"rbmk", "dig", "--fail-on-bogon", "+noall", "+logs", "@10.0.0.53", "A", "www.example.com",
+		},
+		ExpectedErr: errors.New("policy violation: response contains a bogon address: 127.0.0.1"),
+		ExpectedSeq: []ExpectedEvent{
+			{Msg: "connectStart"},
+			{Msg: "connectDone"},
+			{Msg: "dnsQuery"},
+			{Pattern: MatchAnyRead | MatchAnyWrite},
+			{Msg: "dnsResponse"},
+			{Pattern: MatchAnyClose},
+		},
+	},
+
+	{
+		Name: "dnsOverUdpSinkholeCompare",
+		Editors: []ScenarioEditor{
+			ServeDNSSinkhole("127.0.0.1", "10.0.0.53"),
+			ServeDNSSinkhole("127.0.0.1", "10.0.0.54"),
+		},
+		Argv: []string{
+			"rbmk", "dig", "--compare", "+noall", "+logs", "@10.0.0.53", "@10.0.0.54", "A", "www.example.com",
+		},
+		ExpectedErr: nil,
+		ExpectedSeq: []ExpectedEvent{
+			{Msg: "connectStart"},
+			{Msg: "connectDone"},
+			{Msg: "dnsQuery"},
+			{Pattern: MatchAnyRead | MatchAnyWrite},
+			{Msg: "dnsResponse"},
+			{Pattern: MatchAnyClose},
+			{Msg: "connectStart"},
+			{Pattern: MatchAnyClose},
+			{Msg: "connectDone"},
+			{Pattern: MatchAnyClose},
+			{Msg: "dnsQuery"},
+			{Pattern: MatchAnyRead | MatchAnyWrite | MatchAnyClose},
+			{Msg: "dnsResponse"},
+			{Pattern: MatchAnyClose},
+			{Msg: "dnsSweepSummary", Bogons: 2},
+		},
+	},
+
 	//
 	// DNS over TCP
 	//
@@ -112,6 +189,27 @@ var Registry = []ScenarioDescriptor{
 		},
 	},
 
+	{
+		Name:    "dnsOverTlsInsecure",
+		Editors: []ScenarioEditor{},
+		Argv: []string{
+			"rbmk", "dig", "--insecure", "+noall", "+logs", "+tls", "@8.8.8.8", "A", "www.example.com",
+		},
+		ExpectedErr: nil,
+		ExpectedSeq: []ExpectedEvent{
+			{Msg: "connectStart"},
+			{Msg: "connectDone"},
+			{Msg: "tlsHandshakeStart", TLSSkipVerify: true},
+			{Pattern: MatchAnyRead | MatchAnyWrite},
+			{Msg: "tlsHandshakeDone", TLSSkipVerify: true},
+			{Pattern: MatchAnyRead | MatchAnyWrite},
+			{Msg: "dnsQuery"},
+			{Pattern: MatchAnyRead | MatchAnyWrite},
+			{Msg: "dnsResponse"},
+			{Pattern: MatchAnyRead | MatchAnyWrite | MatchAnyClose},
+		},
+	},
+
 	//
 	// DNS over HTTPS
 	//
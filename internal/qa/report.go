@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package qa
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ReportResult is the result of running a scenario with a [*ReportDriver].
+type ReportResult struct {
+	// Name is the scenario name.
+	Name string
+
+	// Failed indicates whether the scenario failed.
+	Failed bool
+
+	// Errors contains the error messages emitted by the scenario.
+	Errors []string
+
+	// Duration is the time it took to run the scenario.
+	Duration time.Duration
+}
+
+// errFailNow is the value we panic with to implement FailNow.
+var errFailNow = errors.New("qa: FailNow called")
+
+// ReportDriver is a [Driver] for running scenarios standalone, i.e.,
+// outside of `go test`, which records whether each scenario passed or
+// failed and allows to write a human-readable report at the end.
+//
+// Use [NewReportDriver] to construct. This driver is not goroutine
+// safe and you must run each scenario using [*ReportDriver.Run].
+type ReportDriver struct {
+	current *ReportResult
+	logs    io.Writer
+	results []ReportResult
+}
+
+var _ Driver = &ReportDriver{}
+
+// NewReportDriver creates a new [*ReportDriver] that writes the
+// messages emitted by the scenarios to the given logs writer.
+func NewReportDriver(logs io.Writer) *ReportDriver {
+	return &ReportDriver{logs: logs}
+}
+
+// Run runs the given function as the scenario with the given name,
+// records the result, and returns whether the scenario passed.
+//
+// We treat a panic occurring while running the function as a failure.
+func (d *ReportDriver) Run(name string, fn func(t Driver)) bool {
+	result := &ReportResult{Name: name}
+	d.current = result
+	t0 := time.Now()
+	func() {
+		defer func() {
+			if r := recover(); r != nil && r != errFailNow {
+				d.Errorf("panic: %v", r)
+			}
+		}()
+		fn(d)
+	}()
+	result.Duration = time.Since(t0)
+	d.results = append(d.results, *result)
+	d.current = nil
+	return !result.Failed
+}
+
+// Results returns the results of the scenarios run so far.
+func (d *ReportDriver) Results() []ReportResult {
+	return d.results
+}
+
+// Failed returns whether any of the scenarios run so far failed.
+func (d *ReportDriver) Failed() bool {
+	for _, result := range d.results {
+		if result.Failed {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteReport writes a human-readable report to the given writer.
+func (d *ReportDriver) WriteReport(w io.Writer) {
+	var failed int
+	for _, result := range d.results {
+		status := "PASS"
+		if result.Failed {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(w, "%s %s (%s)\n", status, result.Name, result.Duration.Round(time.Millisecond))
+		for _, message := range result.Errors {
+			fmt.Fprintf(w, "    %s\n", message)
+		}
+	}
+	fmt.Fprintf(w, "\n%d scenarios, %d passed, %d failed\n",
+		len(d.results), len(d.results)-failed, failed)
+}
+
+// Deadline implements [Driver].
+func (d *ReportDriver) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Errorf implements [Driver].
+func (d *ReportDriver) Errorf(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	d.current.Failed = true
+	d.current.Errors = append(d.current.Errors, message)
+	fmt.Fprintf(d.logs, "%s\n", message)
+}
+
+// FailNow implements [Driver].
+//
+// We stop running the current scenario by panicking with a value
+// that [*ReportDriver.Run] recovers, which works because the checks
+// verifying the scenarios do not expect FailNow to return.
+func (d *ReportDriver) FailNow() {
+	d.current.Failed = true
+	panic(errFailNow)
+}
+
+// Fatalf implements [Driver].
+func (d *ReportDriver) Fatalf(format string, args ...any) {
+	d.Errorf(format, args...)
+	d.FailNow()
+}
+
+// Logf implements [Driver].
+func (d *ReportDriver) Logf(format string, args ...any) {
+	fmt.Fprintf(d.logs, "%s\n", fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package qa_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rbmk-project/rbmk/internal/qa"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportDriver(t *testing.T) {
+	driver := qa.NewReportDriver(io.Discard)
+
+	// run a trivial scenario that succeeds
+	passing := qa.ScenarioDescriptor{
+		Name: "trivialSuccess",
+		Argv: []string{"rbmk", "version"},
+	}
+	require.True(t, driver.Run(passing.Name, func(t qa.Driver) {
+		passing.Run(t, testRunConfig)
+	}))
+
+	// run a trivial scenario that fails and make sure that
+	// FailNow stops the execution of the scenario
+	failing := qa.ScenarioDescriptor{
+		Name:        "trivialFailure",
+		Argv:        []string{"rbmk", "version"},
+		ExpectedErr: errors.New("mocked error"),
+	}
+	var reached bool
+	require.False(t, driver.Run(failing.Name, func(t qa.Driver) {
+		failing.Run(t, testRunConfig)
+		reached = true
+	}))
+	require.False(t, reached, "FailNow should stop the scenario")
+
+	// run a scenario that panics
+	require.False(t, driver.Run("panic", func(t qa.Driver) {
+		panic("mocked panic")
+	}))
+
+	// check the results and the summary
+	require.True(t, driver.Failed())
+	results := driver.Results()
+	require.Len(t, results, 3)
+	require.False(t, results[0].Failed)
+	require.True(t, results[1].Failed)
+	require.NotEmpty(t, results[1].Errors)
+	require.Equal(t, []string{"panic: mocked panic"}, results[2].Errors)
+
+	var report strings.Builder
+	driver.WriteReport(&report)
+	output := report.String()
+	require.Contains(t, output, "PASS trivialSuccess")
+	require.Contains(t, output, "FAIL trivialFailure")
+	require.Contains(t, output, "FAIL panic")
+	require.Contains(t, output, "3 scenarios, 1 passed, 2 failed")
+}
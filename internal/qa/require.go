@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package qa
+
+import "fmt"
+
+// requireTrue fails the current QA execution with the given
+// message unless the given condition holds. Like testify's
+// require package, we invoke Errorf and then FailNow.
+func requireTrue(t Driver, cond bool, format string, args ...any) {
+	if h, ok := t.(helper); ok {
+		h.Helper()
+	}
+	if !cond {
+		t.Errorf(format, args...)
+		t.FailNow()
+	}
+}
+
+// requireNoError is like requireTrue but fails when the given
+// error is not nil and appends the error to the message.
+func requireNoError(t Driver, err error, format string, args ...any) {
+	if h, ok := t.(helper); ok {
+		h.Helper()
+	}
+	if err != nil {
+		t.Errorf("%s: %s", fmt.Sprintf(format, args...), err.Error())
+		t.FailNow()
+	}
+}
+
+// helper is implemented by the [Driver] values that allow marking the
+// calling function as a helper (e.g., [testing.T]), such that failures
+// report the line of the caller of the helper.
+type helper interface {
+	Helper()
+}
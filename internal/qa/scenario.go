@@ -8,12 +8,12 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"log"
 
+	"github.com/rbmk-project/common/cliutils"
 	"github.com/rbmk-project/rbmk/internal/testable"
-	"github.com/rbmk-project/rbmk/pkg/cli"
 	"github.com/rbmk-project/x/netsim"
 	"github.com/rbmk-project/x/netsim/geolink"
-	"github.com/stretchr/testify/require"
 )
 
 // ScenarioEditor modifies a [*netsim.Scenario]. Editors are the building
@@ -55,15 +55,41 @@ type ScenarioDescriptor struct {
 	ExpectedSeq []ExpectedEvent
 }
 
+// RunConfig contains the configuration for [*ScenarioDescriptor.Run].
+type RunConfig struct {
+	// CacheDir is the MANDATORY directory where to cache TLS certificates.
+	CacheDir string
+
+	// NewCommand is the MANDATORY function creating the `rbmk` command.
+	//
+	// We receive this function as a dependency, rather than importing
+	// the package implementing the `rbmk` command, to allow such a
+	// package to include a command running the QA scenarios.
+	NewCommand func() cliutils.Command
+
+	// Logs is the OPTIONAL [io.Writer] receiving the logs of the
+	// network simulation. When nil, they go to the output of the
+	// standard library logger, which the simulation uses.
+	Logs io.Writer
+}
+
 // Run runs the given [*ScenarioDescriptor] using the given [Driver] and
 // sets the failure state accordingly using the [Driver] interface, which
 // can be implemented using, e.g., [*testing.T].
 //
 // This method returns an [io.Reader] from which the caller can read the
 // structured logs generated by running this command.
-func (desc *ScenarioDescriptor) Run(t Driver) io.Reader {
+func (desc *ScenarioDescriptor) Run(t Driver, config *RunConfig) io.Reader {
+	// Redirect the logs of the network simulation, if needed, and
+	// restore the standard library logger output when done.
+	if config.Logs != nil {
+		output := log.Writer()
+		log.SetOutput(config.Logs)
+		defer log.SetOutput(output)
+	}
+
 	// Initialize the scenario and apply all the editors.
-	scenario := MustNewCommonScenario("testdata")
+	scenario := MustNewCommonScenario(config.CacheDir)
 	defer scenario.Close()
 	for _, modifier := range desc.Editors {
 		scenario = modifier(scenario)
@@ -79,7 +105,9 @@ func (desc *ScenarioDescriptor) Run(t Driver) io.Reader {
 	}
 	scenario.Attach(geolink.Extend(stack, linkConfig))
 	testable.DialContext.Set(stack.DialContext)
+	defer testable.DialContext.Set(nil)
 	testable.RootCAs.Set(scenario.RootCAs())
+	defer testable.RootCAs.Set(nil)
 
 	// Override the specific stdout used to generate structured logs.
 	//
@@ -107,17 +135,17 @@ func (desc *ScenarioDescriptor) Run(t Driver) io.Reader {
 	}()
 
 	// Create the main RBMK command.
-	cmd := cli.NewCommand()
+	cmd := config.NewCommand()
 
 	// Execute the given argv.
 	err := cmd.Main(context.Background(), env, desc.Argv...)
 
 	// Check whether the return value is OK.
 	if desc.ExpectedErr != nil {
-		require.EqualError(t, err, desc.ExpectedErr.Error(),
-			"scenario %s should return expected error", desc.Name)
+		requireTrue(t, err != nil && err.Error() == desc.ExpectedErr.Error(),
+			"scenario %s should return %q, got %v", desc.Name, desc.ExpectedErr.Error(), err)
 	} else {
-		require.NoError(t, err, "scenario %s should not return error", desc.Name)
+		requireNoError(t, err, "scenario %s should not return error", desc.Name)
 	}
 
 	// Ensure we've collected all logs before returning the reader.
@@ -161,10 +189,10 @@ func (desc *ScenarioDescriptor) VerifyEvents(t Driver, r io.Reader) {
 	for sx.Scan() {
 		var got Event
 		err := json.Unmarshal(sx.Bytes(), &got)
-		require.NoError(t, err, "failed to parse event")
+		requireNoError(t, err, "failed to parse event")
 		evs = append(evs, &got)
 	}
-	require.NoError(t, sx.Err(), "failed to scan events")
+	requireNoError(t, sx.Err(), "failed to scan events")
 
 	// Loop until we have events or expectations to compare
 	for i, j := 0, 0; i < len(desc.ExpectedSeq) && j < len(evs); {
@@ -18,6 +18,7 @@ import (
 	"net"
 	"os"
 	"sync"
+	"syscall"
 
 	"github.com/rbmk-project/common/cliutils"
 	"github.com/rbmk-project/common/fsx"
@@ -58,6 +59,24 @@ func (dcp *DialContextProvider) Get() DialContextFunc {
 	return fx
 }
 
+// ControlFunc is the type of the [net.Dialer] Control function.
+type ControlFunc func(network, address string, conn syscall.RawConn) error
+
+// GetWithControl is like [*DialContextProvider.Get] but the default dial
+// function uses a [net.Dialer] invoking the given control function, which
+// may be nil. When the dial function has been overridden, we return it
+// unchanged, since the override (e.g., a simulated network stack) is not
+// required to create system sockets the control function could modify.
+func (dcp *DialContextProvider) GetWithControl(control ControlFunc) DialContextFunc {
+	dcp.mu.Lock()
+	defer dcp.mu.Unlock()
+	fx := dcp.fx
+	if fx == nil {
+		fx = (&net.Dialer{Control: control}).DialContext
+	}
+	return fx
+}
+
 // RootCAsProvider provides a thread-safe way to override the root CAs.
 //
 // The zero value is ready to use and uses the system root CAs.
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"slices"
+
+	"github.com/spf13/pflag"
+)
+
+// orderedFlag is an occurrence of a command line flag whose effect
+// depends on its position relative to the dig-style "+" options, such
+// that the last one in argv order wins (e.g., --protocol and +tcp).
+type orderedFlag struct {
+	// Name is the flag name (e.g., "protocol").
+	Name string
+
+	// Value is the flag value (e.g., "tcp").
+	Value string
+
+	// Pos is the number of positional arguments preceding the flag.
+	Pos int
+}
+
+// parseOrdered parses the given arguments like [*pflag.FlagSet.Parse]
+// and returns, in argv order, the occurrences of the flags with the
+// given names, recording their position relative to the positional
+// arguments, which pflag would otherwise lose.
+func parseOrdered(clip *pflag.FlagSet, args []string, names ...string) ([]orderedFlag, error) {
+	var ordered []orderedFlag
+	err := clip.ParseAll(args, func(flag *pflag.Flag, value string) error {
+		if slices.Contains(names, flag.Name) {
+			ordered = append(ordered, orderedFlag{Name: flag.Name, Value: value, Pos: len(clip.Args())})
+		}
+		return clip.Set(flag.Name, value)
+	})
+	return ordered, err
+}
@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestParseOrdered(t *testing.T) {
+	clip := pflag.NewFlagSet("rbmk dig", pflag.ContinueOnError)
+	protocol := clip.String("protocol", "", "")
+	insecure := clip.BoolP("insecure", "k", false, "")
+	ordered, err := parseOrdered(clip, []string{
+		"--protocol", "tcp", "+tls", "-k", "@8.8.8.8", "--protocol=doh", "www.example.com",
+	}, "protocol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Make sure we record the occurrences and their position
+	expect := []orderedFlag{
+		{Name: "protocol", Value: "tcp", Pos: 0},
+		{Name: "protocol", Value: "doh", Pos: 2},
+	}
+	if !reflect.DeepEqual(ordered, expect) {
+		t.Fatalf("expected %+v, got %+v", expect, ordered)
+	}
+
+	// Make sure we still set all the flags and collect the positional arguments
+	if *protocol != "doh" || !*insecure {
+		t.Fatalf("unexpected flag values: %s %v", *protocol, *insecure)
+	}
+	if got := clip.Args(); !reflect.DeepEqual(got, []string{"+tls", "@8.8.8.8", "www.example.com"}) {
+		t.Fatalf("unexpected positional arguments: %v", got)
+	}
+}
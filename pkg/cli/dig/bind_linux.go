@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+//go:build linux
+
+package dig
+
+import "syscall"
+
+// bindInterfaceSupported indicates whether we support --bind-interface.
+const bindInterfaceSupported = true
+
+// newBindInterfaceControl returns a [net.Dialer] Control function that
+// binds each socket to the given network interface using SO_BINDTODEVICE.
+//
+// Note that binding requires the CAP_NET_RAW capability on older
+// kernels (before Linux 5.7) or when the socket is already bound.
+func newBindInterfaceControl(name string) (func(network, address string, conn syscall.RawConn) error, error) {
+	control := func(network, address string, conn syscall.RawConn) error {
+		var serr error
+		err := conn.Control(func(fd uintptr) {
+			serr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, name)
+		})
+		if err != nil {
+			return err
+		}
+		return serr
+	}
+	return control, nil
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+//go:build linux
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestNewBindInterfaceControl(t *testing.T) {
+	dial := func(name string) error {
+		control, err := newBindInterfaceControl(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dialer := &net.Dialer{Control: control}
+		conn, err := dialer.DialContext(context.Background(), "udp", "127.0.0.1:53")
+		if err != nil {
+			return err
+		}
+		conn.Close()
+		return nil
+	}
+
+	t.Run("we can bind to the loopback interface", func(t *testing.T) {
+		err := dial("lo")
+		if errors.Is(err, syscall.EPERM) {
+			t.Skip("binding to an interface requires privileges")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("we fail with a nonexistent interface", func(t *testing.T) {
+		err := dial("rbmk-nonexistent")
+		if errors.Is(err, syscall.EPERM) {
+			t.Skip("binding to an interface requires privileges")
+		}
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -0,0 +1,19 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+//go:build !linux
+
+package dig
+
+import (
+	"errors"
+	"syscall"
+)
+
+// bindInterfaceSupported indicates whether we support --bind-interface.
+const bindInterfaceSupported = false
+
+// newBindInterfaceControl returns an error since we only
+// support binding to a network interface on Linux.
+func newBindInterfaceControl(name string) (func(network, address string, conn syscall.RawConn) error, error) {
+	return nil, errors.New("binding to a network interface is only supported on Linux")
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"net/netip"
+
+	"github.com/miekg/dns"
+)
+
+// bogonPrefixes contains the address prefixes that should not
+// appear in DNS answers for names on the public internet.
+var bogonPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// isBogon returns whether the given address is a bogon, i.e., a
+// private, reserved, or otherwise not publicly routable address.
+//
+// We unmap IPv4-mapped IPv6 addresses before checking.
+func isBogon(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range bogonPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// findBogon returns the first bogon address among the A and AAAA
+// answers of the given response, if any.
+func findBogon(resp *dns.Msg) (netip.Addr, bool) {
+	for _, addr := range answerAddrs(resp) {
+		if isBogon(addr) {
+			return addr, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// answerAddrs returns the unmapped addresses contained
+// in the A and AAAA answers of the given response.
+func answerAddrs(resp *dns.Msg) []netip.Addr {
+	var addrs []netip.Addr
+	for _, ans := range resp.Answer {
+		var ip []byte
+		switch ans := ans.(type) {
+		case *dns.A:
+			ip = ans.A
+		case *dns.AAAA:
+			ip = ans.AAAA
+		default:
+			continue
+		}
+		if addr, ok := netip.AddrFromSlice(ip); ok {
+			addrs = append(addrs, addr.Unmap())
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestIsBogon(t *testing.T) {
+	cases := []struct {
+		addr   string
+		expect bool
+	}{
+		{addr: "8.8.8.8", expect: false},
+		{addr: "104.18.26.120", expect: false},
+		{addr: "2001:4860:4860::8888", expect: false},
+		{addr: "0.0.0.0", expect: true},
+		{addr: "10.0.0.1", expect: true},
+		{addr: "127.0.0.1", expect: true},
+		{addr: "172.16.1.1", expect: true},
+		{addr: "192.168.1.1", expect: true},
+		{addr: "255.255.255.255", expect: true},
+		{addr: "::ffff:10.0.0.1", expect: true},
+		{addr: "::1", expect: true},
+		{addr: "fe80::1", expect: true},
+		{addr: "fd00::1", expect: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.addr, func(t *testing.T) {
+			if got := isBogon(netip.MustParseAddr(tc.addr)); got != tc.expect {
+				t.Fatalf("expected %v, got %v", tc.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"net"
+
+	"github.com/rbmk-project/dnscore"
+)
+
+// newBootstrapLookupHost returns a function suitable for the LookupHostFunc
+// field of [*netcore.Network] that resolves domain names by querying the
+// BootstrapServer over UDP using the given transport.
+//
+// This allows resolving the name of a DoH server without depending on
+// the system resolver, like DoH clients do when bootstrapping.
+func (task *Task) newBootstrapLookupHost(
+	txp dnscore.ResolverTransport) func(ctx context.Context, domain string) ([]string, error) {
+	config := dnscore.NewConfig()
+	address := net.JoinHostPort(task.BootstrapServer, "53")
+	config.AddServer(dnscore.NewServerAddr(dnscore.ProtocolUDP, address))
+	reso := &dnscore.Resolver{Config: config, Transport: txp}
+	return reso.LookupHost
+}
@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/x/netcore"
+)
+
+func TestTaskNewBootstrapLookupHost(t *testing.T) {
+	txp := &mockTransport{
+		responses: map[string][]dns.RR{
+			"9.9.9.9:53": {&dns.A{
+				Hdr: dns.RR_Header{Name: "dns.google.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+				A:   net.ParseIP("8.8.4.4"),
+			}},
+		},
+	}
+
+	// we wire the bootstrap lookup into a network that records the
+	// dialed address rather than actually establishing connections
+	task := newTestTask()
+	task.BootstrapServer = "9.9.9.9"
+	var dialed []string
+	errMocked := errors.New("mocked dial error")
+	netx := &netcore.Network{
+		DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed = append(dialed, address)
+			return nil, errMocked
+		},
+		LookupHostFunc: task.newBootstrapLookupHost(txp),
+	}
+
+	_, err := netx.DialContext(context.Background(), "tcp", "dns.google:443")
+	if !errors.Is(err, errMocked) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dialed) != 1 || dialed[0] != "8.8.4.4:443" {
+		t.Fatalf("unexpected dialed addresses: %v", dialed)
+	}
+}
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"log/slog"
+	"net"
+	"path/filepath"
+
+	"github.com/rbmk-project/common/fsx"
+	"github.com/rbmk-project/x/netcore"
+)
+
+// certDumper writes the peer certificates observed during
+// successful TLS handshakes as PEM files inside a directory.
+//
+// We name each file using the SHA256 of the certificate, such that
+// we write each distinct certificate only once.
+type certDumper struct {
+	// Dir is the MANDATORY directory where to write the certificates.
+	Dir string
+
+	// FS is the MANDATORY file system to use.
+	FS fsx.FS
+
+	// Logger is the MANDATORY logger to use.
+	Logger *slog.Logger
+}
+
+// wrap wraps a function creating TLS client connections such that
+// the returned connections dump the certificates after the handshake.
+func (cd *certDumper) wrap(
+	newConn func(conn net.Conn, config *tls.Config) netcore.TLSConn,
+) func(conn net.Conn, config *tls.Config) netcore.TLSConn {
+	return func(conn net.Conn, config *tls.Config) netcore.TLSConn {
+		return &certDumpingConn{TLSConn: newConn(conn, config), dumper: cd}
+	}
+}
+
+// dump writes each of the given certificates to a PEM file unless a file
+// with the same name already exists and logs the path of each certificate.
+//
+// Failing to write a certificate does not cause the measurement to
+// fail, so we log the error and move on to the next certificate.
+func (cd *certDumper) dump(ctx context.Context, certs []*x509.Certificate) {
+	for idx, cert := range certs {
+		sum := sha256.Sum256(cert.Raw)
+		path := filepath.Join(cd.Dir, hex.EncodeToString(sum[:])+".pem")
+		err := cd.writeIfMissing(path, cert)
+		cd.Logger.InfoContext(
+			ctx,
+			"tlsCertDump",
+			slog.Int("certIndex", idx),
+			slog.String("certPath", path),
+			slog.String("certSubject", cert.Subject.String()),
+			slog.Any("err", err),
+		)
+	}
+}
+
+// writeIfMissing writes the certificate in PEM format to the
+// given path, unless the path already exists.
+func (cd *certDumper) writeIfMissing(path string, cert *x509.Certificate) error {
+	if _, err := cd.FS.Stat(path); err == nil {
+		return nil
+	}
+	filep, err := cd.FS.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(filep, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+		filep.Close()
+		return err
+	}
+	return filep.Close()
+}
+
+// certDumpingConn is a [netcore.TLSConn] that dumps the peer
+// certificates using a [*certDumper] after a successful handshake.
+type certDumpingConn struct {
+	netcore.TLSConn
+	dumper *certDumper
+}
+
+// HandshakeContext implements [netcore.TLSConn].
+func (c *certDumpingConn) HandshakeContext(ctx context.Context) error {
+	if err := c.TLSConn.HandshakeContext(ctx); err != nil {
+		return err
+	}
+	c.dumper.dump(ctx, c.TLSConn.ConnectionState().PeerCertificates)
+	return nil
+}
@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rbmk-project/common/fsx"
+	"github.com/rbmk-project/common/selfsignedcert"
+	"github.com/rbmk-project/x/netcore"
+)
+
+// mockTLSConn is a [netcore.TLSConn] with a mocked handshake.
+type mockTLSConn struct {
+	net.Conn
+	certs []*x509.Certificate
+	err   error
+}
+
+// ConnectionState implements [netcore.TLSConn].
+func (c *mockTLSConn) ConnectionState() tls.ConnectionState {
+	return tls.ConnectionState{PeerCertificates: c.certs}
+}
+
+// HandshakeContext implements [netcore.TLSConn].
+func (c *mockTLSConn) HandshakeContext(ctx context.Context) error {
+	return c.err
+}
+
+// newTestCert returns a new self-signed certificate for the given name.
+func newTestCert(t *testing.T, name string) *x509.Certificate {
+	config := selfsignedcert.NewConfigExampleCom()
+	config.CommonName = name
+	config.DNSNames = []string{name}
+	block, _ := pem.Decode(selfsignedcert.New(config).CertPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestCertDumper(t *testing.T) {
+	certs := []*x509.Certificate{
+		newTestCert(t, "www.example.com"),
+		newTestCert(t, "www.example.org"),
+	}
+
+	// newDumpingConn returns a dumping conn writing into dir and logs
+	newDumpingConn := func(dir string, logs *strings.Builder, err error) netcore.TLSConn {
+		dumper := &certDumper{Dir: dir, FS: fsx.OsFS{}, Logger: newTestLogger(logs)}
+		newConn := dumper.wrap(func(conn net.Conn, config *tls.Config) netcore.TLSConn {
+			return &mockTLSConn{Conn: conn, certs: certs, err: err}
+		})
+		return newConn(nil, &tls.Config{})
+	}
+
+	t.Run("we write each certificate once", func(t *testing.T) {
+		dir := t.TempDir()
+		var logs strings.Builder
+		for range 2 {
+			if err := newDumpingConn(dir, &logs, nil).HandshakeContext(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("expected two files, got %d", len(entries))
+		}
+		for _, entry := range entries {
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if block, _ := pem.Decode(data); block == nil || block.Type != "CERTIFICATE" {
+				t.Fatalf("invalid PEM file: %s", entry.Name())
+			}
+			if !strings.Contains(logs.String(), entry.Name()) {
+				t.Fatalf("path not logged: %s", entry.Name())
+			}
+		}
+		if count := strings.Count(logs.String(), `"msg":"tlsCertDump"`); count != 4 {
+			t.Fatalf("expected four log events, got %d", count)
+		}
+	})
+
+	t.Run("we do not write anything on handshake failure", func(t *testing.T) {
+		dir := t.TempDir()
+		var logs strings.Builder
+		expect := errors.New("mocked error")
+		if err := newDumpingConn(dir, &logs, expect).HandshakeContext(context.Background()); err != expect {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 || logs.Len() != 0 {
+			t.Fatalf("unexpected dump: %v %q", entries, logs.String())
+		}
+	})
+}
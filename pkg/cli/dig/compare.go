@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// compare sends the same query to each server in CompareServers and
+// writes the differences between the response of the first server and
+// the response of each other server to the DiffWriter.
+//
+// We return the errors that occurred when querying the servers, if any,
+// after having written the differences for the servers that responded.
+func (task *Task) compare(
+	ctx context.Context,
+	txp dnsTransport,
+	protocol dnscore.Protocol,
+	query *dns.Msg,
+) error {
+	// Query each server in order and collect the valid responses
+	var (
+		errv      []error
+		responses = make([]*dns.Msg, len(task.CompareServers))
+	)
+	for idx, address := range task.CompareServers {
+		resp, err := task.exchange(ctx, txp, protocol, address, query)
+		if err == nil {
+			err = dnscore.ValidateResponse(query, resp)
+		}
+		if err != nil {
+			errv = append(errv, fmt.Errorf("%s: %w", address, err))
+			continue
+		}
+		responses[idx] = resp
+	}
+
+	// Diff the first response with each of the other responses
+	for idx := 1; idx < len(responses); idx++ {
+		if responses[0] == nil || responses[idx] == nil {
+			continue
+		}
+		diff := diffResponses(responses[0], responses[idx])
+		label := fmt.Sprintf("@%s vs @%s", task.CompareServers[0], task.CompareServers[idx])
+		diff.write(task.DiffWriter, label)
+	}
+	return errors.Join(errv...)
+}
+
+// responseDiff contains the differences between two DNS responses.
+type responseDiff struct {
+	// RcodeA is the RCODE of the first response.
+	RcodeA int
+
+	// RcodeB is the RCODE of the second response.
+	RcodeB int
+
+	// OnlyA contains the answers only present in the first response.
+	OnlyA []string
+
+	// OnlyB contains the answers only present in the second response.
+	OnlyB []string
+}
+
+// diffResponses returns the differences between two DNS responses.
+//
+// We compare the RCODEs and the answer sets, ignoring the TTLs and the
+// order in which the answers appear inside the responses.
+func diffResponses(respA, respB *dns.Msg) *responseDiff {
+	answersA, answersB := answerSet(respA), answerSet(respB)
+	diff := &responseDiff{
+		RcodeA: respA.Rcode,
+		RcodeB: respB.Rcode,
+	}
+	for _, entry := range answersA {
+		if !slices.Contains(answersB, entry) {
+			diff.OnlyA = append(diff.OnlyA, entry)
+		}
+	}
+	for _, entry := range answersB {
+		if !slices.Contains(answersA, entry) {
+			diff.OnlyB = append(diff.OnlyB, entry)
+		}
+	}
+	return diff
+}
+
+// answerSet returns the sorted and deduplicated answers of a response
+// formatted as strings that do not include the TTL.
+func answerSet(resp *dns.Msg) []string {
+	var entries []string
+	for _, ans := range resp.Answer {
+		hdr := ans.Header()
+		value := strings.TrimPrefix(ans.String(), hdr.String())
+		entries = append(entries, fmt.Sprintf("%s\t%s\t%s\t%s",
+			hdr.Name, dns.ClassToString[hdr.Class], dns.TypeToString[hdr.Rrtype], value))
+	}
+	slices.Sort(entries)
+	return slices.Compact(entries)
+}
+
+// Equal returns whether the two responses have the same RCODE and answers.
+func (diff *responseDiff) Equal() bool {
+	return diff.RcodeA == diff.RcodeB && len(diff.OnlyA) <= 0 && len(diff.OnlyB) <= 0
+}
+
+// write writes the differences to the given [io.Writer] using the
+// given label to identify the first and second response.
+func (diff *responseDiff) write(w io.Writer, label string) {
+	fmt.Fprintf(w, "\n;; Diff: %s\n", label)
+	rcodeA, rcodeB := dns.RcodeToString[diff.RcodeA], dns.RcodeToString[diff.RcodeB]
+	if diff.RcodeA != diff.RcodeB {
+		fmt.Fprintf(w, ";; RCODE differs: %s vs %s\n", rcodeA, rcodeB)
+	} else {
+		fmt.Fprintf(w, ";; RCODE: %s\n", rcodeA)
+	}
+	for _, entry := range diff.OnlyA {
+		fmt.Fprintf(w, "-%s\n", entry)
+	}
+	for _, entry := range diff.OnlyB {
+		fmt.Fprintf(w, "+%s\n", entry)
+	}
+	if diff.Equal() {
+		fmt.Fprintf(w, ";; No differences\n")
+	}
+}
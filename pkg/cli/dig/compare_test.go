@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+func TestTaskCompare(t *testing.T) {
+	txp := &mockTransport{
+		responses: map[string][]dns.RR{
+			"8.8.8.8:53":  {newTestA("93.184.216.34")},
+			"10.0.0.1:53": {newTestA("10.10.34.34")},
+		},
+	}
+
+	t.Run("with differing answers", func(t *testing.T) {
+		var out strings.Builder
+		task := newTestTask()
+		task.CompareServers = []string{"8.8.8.8", "10.0.0.1"}
+		task.DiffWriter = &out
+		err := task.compare(context.Background(), txp, dnscore.ProtocolUDP, newTestQuery(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := strings.Join([]string{
+			"",
+			";; Diff: @8.8.8.8 vs @10.0.0.1",
+			";; RCODE: NOERROR",
+			"-www.example.com.\tIN\tA\t93.184.216.34",
+			"+www.example.com.\tIN\tA\t10.10.34.34",
+			"",
+		}, "\n")
+		if got := out.String(); got != expect {
+			t.Fatalf("expected %q, got %q", expect, got)
+		}
+	})
+
+	t.Run("with a failing server", func(t *testing.T) {
+		var out strings.Builder
+		task := newTestTask()
+		task.CompareServers = []string{"8.8.8.8", "10.0.0.2"}
+		task.DiffWriter = &out
+		err := task.compare(context.Background(), txp, dnscore.ProtocolUDP, newTestQuery(t))
+		if err == nil || err.Error() != "10.0.0.2: mocked error" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out.Len() != 0 {
+			t.Fatalf("expected no diff, got %q", out.String())
+		}
+	})
+}
+
+func TestDiffResponses(t *testing.T) {
+	t.Run("equal responses ignoring TTL and order", func(t *testing.T) {
+		respA, respB := &dns.Msg{}, &dns.Msg{}
+		respA.Answer = []dns.RR{newTestA("1.1.1.1"), newTestA("8.8.8.8")}
+		respB.Answer = []dns.RR{newTestA("8.8.8.8"), newTestA("1.1.1.1")}
+		respB.Answer[0].Header().Ttl = 10
+		if diff := diffResponses(respA, respB); !diff.Equal() {
+			t.Fatalf("expected equal responses, got %+v", diff)
+		}
+	})
+
+	t.Run("differing RCODEs", func(t *testing.T) {
+		respA, respB := &dns.Msg{}, &dns.Msg{}
+		respB.Rcode = dns.RcodeNameError
+		diff := diffResponses(respA, respB)
+		if diff.Equal() {
+			t.Fatal("expected different responses")
+		}
+		var out strings.Builder
+		diff.write(&out, "@a vs @b")
+		if !strings.Contains(out.String(), ";; RCODE differs: NOERROR vs NXDOMAIN\n") {
+			t.Fatalf("unexpected output: %q", out.String())
+		}
+	})
+}
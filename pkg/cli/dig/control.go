@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"syscall"
+
+	"github.com/rbmk-project/rbmk/internal/testable"
+)
+
+// dialerControl returns the [net.Dialer] Control function that binds
+// each socket to the BindInterface and sets the TCPMSS, or nil when
+// neither of these fields is set.
+func (task *Task) dialerControl() (testable.ControlFunc, error) {
+	var controls []testable.ControlFunc
+	if task.BindInterface != "" {
+		control, err := newBindInterfaceControl(task.BindInterface)
+		if err != nil {
+			return nil, err
+		}
+		controls = append(controls, control)
+	}
+	if task.TCPMSS > 0 {
+		control, err := newTCPMSSControl(task.TCPMSS)
+		if err != nil {
+			return nil, err
+		}
+		controls = append(controls, control)
+	}
+	if len(controls) <= 0 {
+		return nil, nil
+	}
+	control := func(network, address string, conn syscall.RawConn) error {
+		for _, control := range controls {
+			if err := control(network, address, conn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return control, nil
+}
@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// errAnswerCount indicates that the number of answers of the queried
+// type is outside the range set using the CountAnswers field.
+var errAnswerCount = errors.New("answer count outside range")
+
+// answerCountRange is the range of the number of answers of the
+// queried type that we accept (see checkCountAnswers).
+type answerCountRange struct {
+	// Min is the minimum number of answers.
+	Min int
+
+	// Max is the maximum number of answers, meaningful when HasMax is set.
+	Max int
+
+	// HasMax indicates whether there is an upper bound.
+	HasMax bool
+}
+
+// parseCountAnswers parses the MIN[:MAX] value of --count-answers.
+//
+// Because at least zero answers is not a check, we reject a zero MIN
+// without MAX rather than guessing whether the user meant `0:0`.
+func parseCountAnswers(value string) (*answerCountRange, error) {
+	minValue, maxValue, hasMax := strings.Cut(value, ":")
+	minCount, err := strconv.Atoi(minValue)
+	if err != nil || minCount < 0 {
+		return nil, fmt.Errorf("invalid --count-answers range: %q", value)
+	}
+	if !hasMax && minCount == 0 {
+		return nil, fmt.Errorf("invalid --count-answers range: %q (use 0:0 to require no answers)", value)
+	}
+	if !hasMax {
+		return &answerCountRange{Min: minCount}, nil
+	}
+	maxCount, err := strconv.Atoi(maxValue)
+	if err != nil || maxCount < minCount {
+		return nil, fmt.Errorf("invalid --count-answers range: %q", value)
+	}
+	return &answerCountRange{Min: minCount, Max: maxCount, HasMax: true}, nil
+}
+
+// countAnswersOfType returns the number of answers of the given type.
+func countAnswersOfType(resp *dns.Msg, qtype uint16) int {
+	var count int
+	for _, ans := range resp.Answer {
+		if ans.Header().Rrtype == qtype {
+			count++
+		}
+	}
+	return count
+}
+
+// checkCountAnswers checks whether the number of answers of the queried
+// type is within the range set using the CountAnswers field.
+//
+// When the field is set, we emit a dnsAnswerCount structured log event,
+// where a negative dnsCountMax means there is no upper bound, and, on
+// failure, we return an error wrapping [errAnswerCount].
+func (task *Task) checkCountAnswers(ctx context.Context, logger *slog.Logger, query, resp *dns.Msg) error {
+	bounds := task.CountAnswers
+	if bounds == nil {
+		return nil
+	}
+	if len(query.Question) <= 0 {
+		return nil
+	}
+	qtype := query.Question[0].Qtype
+	count := countAnswersOfType(resp, qtype)
+	maxCount := -1
+	if bounds.HasMax {
+		maxCount = bounds.Max
+	}
+	logger.InfoContext(
+		ctx,
+		"dnsAnswerCount",
+		slog.Int("dnsAnswerCount", count),
+		slog.String("dnsAnswerType", dns.TypeToString[qtype]),
+		slog.Int("dnsCountMax", maxCount),
+		slog.Int("dnsCountMin", bounds.Min),
+		slog.Time("t", time.Now()),
+	)
+	switch {
+	case count < bounds.Min:
+		return fmt.Errorf("%w: %d %s answers < %d", errAnswerCount,
+			count, dns.TypeToString[qtype], bounds.Min)
+	case bounds.HasMax && count > bounds.Max:
+		return fmt.Errorf("%w: %d %s answers > %d", errAnswerCount,
+			count, dns.TypeToString[qtype], bounds.Max)
+	default:
+		return nil
+	}
+}
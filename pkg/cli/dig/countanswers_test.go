@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseCountAnswers(t *testing.T) {
+	cases := []struct {
+		value  string
+		expect answerCountRange
+		fails  bool
+	}{
+		{value: "3", expect: answerCountRange{Min: 3}},
+		{value: "3:8", expect: answerCountRange{Min: 3, Max: 8, HasMax: true}},
+		{value: "0:1", expect: answerCountRange{Min: 0, Max: 1, HasMax: true}},
+		{value: "3:3", expect: answerCountRange{Min: 3, Max: 3, HasMax: true}},
+		{value: "0:0", expect: answerCountRange{Min: 0, Max: 0, HasMax: true}},
+		{value: "0", fails: true},
+		{value: "", fails: true},
+		{value: "-1", fails: true},
+		{value: "3:", fails: true},
+		{value: "3:0", fails: true},
+		{value: "8:3", fails: true},
+		{value: "a:b", fails: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			bounds, err := parseCountAnswers(tc.value)
+			if tc.fails {
+				if err == nil || !strings.HasPrefix(err.Error(), "invalid --count-answers range") {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *bounds != tc.expect {
+				t.Fatalf("expected %+v, got %+v", tc.expect, *bounds)
+			}
+		})
+	}
+}
+
+func TestTaskCheckCountAnswers(t *testing.T) {
+	// newResponse returns a response with the given number of A
+	// answers preceded by a CNAME answer, which we should not count.
+	newResponse := func(count int) *dns.Msg {
+		resp := &dns.Msg{}
+		resp.SetReply(newTestQuery(t))
+		resp.Answer = append(resp.Answer, &dns.CNAME{
+			Hdr:    dns.RR_Header{Name: "www.example.com.", Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 300},
+			Target: "pool.example.com.",
+		})
+		for range count {
+			resp.Answer = append(resp.Answer, newTestA("93.184.216.34"))
+		}
+		return resp
+	}
+
+	cases := []struct {
+		name   string
+		bounds answerCountRange
+		count  int
+		expect error
+	}{
+		{name: "below the minimum", bounds: answerCountRange{Min: 3}, count: 2, expect: errAnswerCount},
+		{name: "within the range", bounds: answerCountRange{Min: 3, Max: 5, HasMax: true}, count: 4, expect: nil},
+		{name: "without an upper bound", bounds: answerCountRange{Min: 3}, count: 16, expect: nil},
+		{name: "above the maximum", bounds: answerCountRange{Min: 3, Max: 5, HasMax: true}, count: 6, expect: errAnswerCount},
+		{name: "exactly zero with zero", bounds: answerCountRange{HasMax: true}, count: 0, expect: nil},
+		{name: "exactly zero with one", bounds: answerCountRange{HasMax: true}, count: 1, expect: errAnswerCount},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs strings.Builder
+			task := newTestTask()
+			task.CountAnswers = &tc.bounds
+			err := task.checkCountAnswers(context.Background(),
+				newTestLogger(&logs), newTestQuery(t), newResponse(tc.count))
+			if !errors.Is(err, tc.expect) {
+				t.Fatalf("expected %v, got %v", tc.expect, err)
+			}
+			var ev struct {
+				Msg            string `json:"msg"`
+				DNSAnswerCount int    `json:"dnsAnswerCount"`
+				DNSAnswerType  string `json:"dnsAnswerType"`
+			}
+			if err := json.Unmarshal([]byte(logs.String()), &ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Msg != "dnsAnswerCount" || ev.DNSAnswerCount != tc.count || ev.DNSAnswerType != "A" {
+				t.Fatalf("unexpected event: %+v", ev)
+			}
+		})
+	}
+
+	t.Run("without a range", func(t *testing.T) {
+		var logs strings.Builder
+		task := newTestTask()
+		err := task.checkCountAnswers(context.Background(),
+			newTestLogger(&logs), newTestQuery(t), newResponse(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if logs.Len() > 0 {
+			t.Fatalf("unexpected logs: %s", logs.String())
+		}
+	})
+}
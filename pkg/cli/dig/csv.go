@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"encoding/csv"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// csvHeader is the header row we write before the CSV rows.
+var csvHeader = []string{"name", "type", "ttl", "value"}
+
+// formatCSV returns the given rows, which should have the same columns
+// as the csvHeader, formatted as CSV records.
+func formatCSV(rows ...[]string) string {
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	writer.WriteAll(rows) // cannot fail when writing to a strings.Builder
+	return builder.String()
+}
+
+// csvRows returns a row for each answer RR of the DNS response, where
+// the value column contains the type-specific fields of the answer in
+// presentation format (e.g., the address for A and AAAA answers).
+func csvRows(response *dns.Msg) [][]string {
+	var rows [][]string
+	for _, ans := range response.Answer {
+		hdr := ans.Header()
+		rows = append(rows, []string{
+			hdr.Name,
+			dns.TypeToString[hdr.Rrtype],
+			strconv.FormatUint(uint64(hdr.Ttl), 10),
+			strings.TrimPrefix(ans.String(), hdr.String()),
+		})
+	}
+	return rows
+}
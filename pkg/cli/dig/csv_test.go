@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestFormatCSV(t *testing.T) {
+	hdr := func(rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{Name: "www.example.com.", Rrtype: rrtype, Class: dns.ClassINET, Ttl: 300}
+	}
+	resp := &dns.Msg{}
+	resp.SetReply(newTestQuery(t))
+	resp.Answer = []dns.RR{
+		&dns.CNAME{Hdr: hdr(dns.TypeCNAME), Target: "example.com."},
+		&dns.HINFO{Hdr: hdr(dns.TypeHINFO), Cpu: "INTEL-386", Os: "Linux 2.0"},
+		&dns.MX{Hdr: hdr(dns.TypeMX), Preference: 10, Mx: "mail.example.com."},
+		newTestA("93.184.216.34"),
+	}
+	expect := strings.Join([]string{
+		"name,type,ttl,value",
+		"www.example.com.,CNAME,300,example.com.",
+		`www.example.com.,HINFO,300,"""INTEL-386"" ""Linux 2.0"""`,
+		"www.example.com.,MX,300,10 mail.example.com.",
+		"www.example.com.,A,300,93.184.216.34",
+		"",
+	}, "\n")
+	got := formatCSV(csvHeader) + formatCSV(csvRows(resp)...)
+	if got != expect {
+		t.Fatalf("expected %q, got %q", expect, got)
+	}
+}
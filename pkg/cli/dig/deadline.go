@@ -0,0 +1,31 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"fmt"
+	"time"
+)
+
+// parseDeadline parses an RFC3339 deadline and ensures
+// that it is not in the past with respect to now.
+func parseDeadline(value string, now time.Time) (time.Time, error) {
+	deadline, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --deadline: %w", err)
+	}
+	if !deadline.After(now) {
+		return time.Time{}, fmt.Errorf("--deadline is in the past: %s", value)
+	}
+	return deadline, nil
+}
+
+// deadline returns the earlier between now plus the given
+// timeout and the task Deadline, if the latter is set.
+func (task *Task) deadline(now time.Time, timeout time.Duration) time.Time {
+	deadline := now.Add(timeout)
+	if !task.Deadline.IsZero() && task.Deadline.Before(deadline) {
+		deadline = task.Deadline
+	}
+	return deadline
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDeadline(t *testing.T) {
+	now := time.Date(2024, 12, 25, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		value   string
+		expect  time.Time
+		failure bool
+	}{{
+		name:   "valid deadline in the future",
+		value:  "2024-12-25T10:00:30Z",
+		expect: now.Add(30 * time.Second),
+	}, {
+		name:   "valid deadline with time zone offset",
+		value:  "2024-12-25T11:00:30+01:00",
+		expect: now.Add(30 * time.Second),
+	}, {
+		name:    "deadline in the past",
+		value:   "2024-12-25T09:59:59Z",
+		failure: true,
+	}, {
+		name:    "deadline equal to now",
+		value:   "2024-12-25T10:00:00Z",
+		failure: true,
+	}, {
+		name:    "invalid timestamp",
+		value:   "tomorrow",
+		failure: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseDeadline(tc.value, now)
+			if tc.failure {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tc.expect) {
+				t.Fatalf("expected %s, got %s", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestTaskDeadline(t *testing.T) {
+	now := time.Date(2024, 12, 25, 10, 0, 0, 0, time.UTC)
+	const timeout = 5 * time.Second
+
+	cases := []struct {
+		name     string
+		deadline time.Time
+		expect   time.Time
+	}{{
+		name:     "no deadline uses the timeout",
+		deadline: time.Time{},
+		expect:   now.Add(timeout),
+	}, {
+		name:     "deadline earlier than the timeout",
+		deadline: now.Add(time.Second),
+		expect:   now.Add(time.Second),
+	}, {
+		name:     "deadline later than the timeout",
+		deadline: now.Add(time.Minute),
+		expect:   now.Add(timeout),
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := newTestTask()
+			task.Deadline = tc.deadline
+			if got := task.deadline(now, timeout); !got.Equal(tc.expect) {
+				t.Fatalf("expected %s, got %s", tc.expect, got)
+			}
+		})
+	}
+}
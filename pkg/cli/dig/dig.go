@@ -9,7 +9,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/netip"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rbmk-project/common/cliutils"
 	"github.com/rbmk-project/common/closepool"
@@ -46,52 +49,195 @@ func (cmd command) Main(ctx context.Context, env cliutils.Environment, argv ...s
 
 	// 2. create an initial task to be filled according to the command line arguments
 	task := &Task{
-		LogsWriter:     io.Discard,
-		Name:           "",
-		Protocol:       "udp",
-		QueryType:      "A",
-		QueryWriter:    io.Discard,
-		ResponseWriter: env.Stdout(),
-		ShortWriter:    io.Discard,
-		ServerAddr:     "8.8.8.8",
-		ServerPort:     "53",
-		URLPath:        "/dns-query",
-		WaitDuplicates: false,
+		ALPN:              nil,
+		BindInterface:     "",
+		BootstrapServer:   "",
+		CampaignID:        "",
+		CertDumpDir:       "",
+		CheckingDisabled:  false,
+		ClientSubnet:      netip.Prefix{},
+		CompareServers:    nil,
+		CountAnswers:      nil,
+		Deadline:          time.Time{},
+		DiffWriter:        env.Stdout(),
+		DuplicatesTimeout: 0,
+		EDNSBufferSize:    0,
+		EDNSFlags:         0,
+		Expect:            nil,
+		FailOnBogon:       false,
+		FailOnEmpty:       false,
+		FS:                env.FS(),
+		HexDumpWriter:     io.Discard,
+		Insecure:          false,
+		KeepaliveIdle:     0,
+		LogsWriter:        io.Discard,
+		MinTTL:            0,
+		Name:              "",
+		NoRecursion:       false,
+		NoValidate:        false,
+		OutputDir:         "",
+		OutputFormat:      outputFormatDig,
+		ParallelServers:   nil,
+		PhasesWriter:      nil,
+		PollInterval:      0,
+		PollTimeout:       0,
+		PollUntil:         "",
+		Protocol:          "udp",
+		QueryID:           nil,
+		QueryIDWriter:     io.Discard,
+		QueryType:         "A",
+		QueryWriter:       io.Discard,
+		RawQuery:          nil,
+		RepeatInterval:    0,
+		RepeatMax:         0,
+		ResponseWriter:    env.Stdout(),
+		RetryProtocols:    nil,
+		RootCAs:           nil,
+		ShortWriter:       io.Discard,
+		ServerAddr:        "8.8.8.8",
+		ServerPort:        "53",
+		Servers:           nil,
+		SummaryFile:       "",
+		TCPFallback:       false,
+		TCPKeepalive:      false,
+		TCPMSS:            0,
+		URLPath:           "/dns-query",
+		WaitDuplicates:    false,
 	}
 
 	// 3. create command line parser
 	clip := pflag.NewFlagSet("rbmk dig", pflag.ContinueOnError)
 
 	// 4. add flags to the parser
+	alpn := clip.StringSlice("alpn", nil, "comma-separated ALPN list for DoT and DoH")
+	clip.Bool("answers-only", false, "alias for --output-format=answers")
+	bindiface := clip.String("bind-interface", "", "bind the outgoing sockets to the given interface (Linux only)")
+	cafiles := clip.StringArray("ca-file", nil, "PEM file containing the root CAs to use")
+	caSystem := clip.Bool("ca-system-roots", false, "add the --ca-file root CAs to the system's root CAs")
+	campaignID := clip.String("campaign-id", "", "campaign identifier to include in each log record")
+	certdump := clip.String("cert-dump-dir", "", "directory where to write the TLS peer certificates")
+	compare := clip.Bool("compare", false, "query multiple servers and show differences")
+	countAnswers := clip.String("count-answers", "", "fail unless the answers of the queried type are at least MIN or within MIN:MAX (use 0:0 for no answers)")
+	clip.Bool("csv", false, "alias for --output-format=csv")
+	deadline := clip.String("deadline", "", "RFC3339 time by which the query must complete")
+	dohHost := clip.String("doh-host", "", "DoH server name to resolve using @SERVER over UDP")
+	dohPath := clip.String("doh-path", "/dns-query", "URL path to use with DoH")
+	duptimeout := clip.Duration("duplicates-timeout", 0, "how long to collect duplicate responses (e.g., 2s)")
+	ednsflags := clip.String("edns-flags", "", "comma-separated EDNS0 flags (e.g., do,co or 0x0001)")
+	expect := clip.StringSlice("expect", nil, "comma-separated addresses the answers must contain")
+	failOnBogon := clip.Bool("fail-on-bogon", false, "fail if any answer is a bogon address")
+	failOnEmpty := clip.Bool("fail-on-empty", false, "fail if the response contains no answers")
+	helpTypes := clip.Bool("help-types", false, "print the supported query types and exit")
+	hexdump := clip.Bool("hex-dump", false, "write the raw query and response bytes to stderr")
+	insecure := clip.BoolP("insecure", "k", false, "skip TLS certificate verification")
 	logfile := clip.String("logs", "", "path where to write structured logs")
 	measure := clip.Bool("measure", false, "do not exit 1 on measurement failure")
+	minTTL := clip.Uint32("min-ttl", 0, "fail if any answer has a TTL below the given seconds")
+	novalidate := clip.Bool("no-validate", false, "do not validate the response or fail on error RCODEs")
+	norecurse := clip.Bool("norecurse", false, "clear the recursion desired (RD) bit")
+	clip.String("output-format", "dig", "output format: dig, answers, csv, json, or short")
+	outputdir := clip.String("output-dir", "", "directory where to write each query result as JSON")
+	pollInterval := clip.Duration("poll-interval", time.Second, "interval between queries with --until-noerror or --until-nxdomain")
+	pollTimeout := clip.Duration("poll-timeout", time.Minute, "maximum polling time with --until-noerror or --until-nxdomain")
+	printQueryID := clip.Bool("print-query-id", false, "write the query ID to stderr and to the logs")
+	clip.String("protocol", "", "protocol to use: udp, tcp, dot, or doh (the last of this flag and the + options wins)")
+	queryID := clip.String("query-id", "", "use the given query ID rather than a random one")
+	rawquery := clip.String("raw-query", "", "file containing the raw query bytes to send")
+	recursive := clip.Bool("recursive", false, "set the recursion desired (RD) bit (default)")
+	repeatMax := clip.Int("repeat-max", 10, "maximum number of queries to send with --repeat-until-change")
+	repeatChange := clip.Duration("repeat-until-change", 0, "repeat the query at the given interval until the answers change")
+	retryProtos := clip.StringSlice("retry-protocols", nil, "comma-separated protocols to try in order (e.g., udp,tcp,dot)")
+	resolvconf := clip.Bool("server-from-resolv-conf", false, "query the servers listed in /etc/resolv.conf")
+	parallel := clip.Bool("servers-parallel", false, "query multiple servers concurrently and merge the answers")
+	stub := clip.Bool("stub", false, "alias for --norecurse")
+	summaryfile := clip.String("summary-json", "", "path where to write a JSON summary of the run")
+	tcpFallback := clip.Bool("tcp-fallback", false, "retry using TCP when the UDP response is truncated")
+	tcpKeepalive := clip.Duration("tcp-keepalive", 0, "keep the TCP or DoT connection idle for the given time")
+	tcpMSS := clip.Int("tcp-mss", 0, "set the TCP maximum segment size and report whether the response completed (Linux only)")
+	tracePhases := clip.Bool("trace-phases", false, "print the timing of the connect, TLS handshake, and query phases")
+	untilNoError := clip.Bool("until-noerror", false, "poll until the name resolves to valid answers")
+	untilNXDomain := clip.Bool("until-nxdomain", false, "poll until the name does not exist")
+	waitAllDups := clip.Bool("wait-all-duplicates", false, "use UDP and print all the duplicate responses")
+
+	// 5. parse command line arguments, recording where the flags that
+	// interact with the "+" options appear such that the last one wins
+	ordered, err := parseOrdered(clip, argv[1:], "answers-only", "csv", "output-format", "protocol")
+	if err != nil {
+		return failUsage(env, err)
+	}
 
-	// 5. parse command line arguments
-	if err := clip.Parse(argv[1:]); err != nil {
-		fmt.Fprintf(env.Stderr(), "rbmk dig: %s\n", err.Error())
-		fmt.Fprintf(env.Stderr(), "Run `rbmk dig --help` for usage.\n")
-		return err
+	// 6. honour requests for printing the supported query types
+	if *helpTypes {
+		writeQueryTypes(env.Stdout())
+		return nil
 	}
 
-	// 6. make sure we have at least one argument
+	// 7. make sure we have at least one argument
 	positional := clip.Args()
 	if len(positional) < 1 {
-		err := errors.New("missing name to resolve")
-		fmt.Fprintf(env.Stderr(), "rbmk dig: %s\n", err.Error())
-		fmt.Fprintf(env.Stderr(), "Run `rbmk dig --help` for usage.\n")
-		return err
+		return failUsage(env, errors.New("missing name to resolve"))
 	}
 
-	// 7. parse dig-style positional command line arguments
+	// 8. parse dig-style positional command line arguments, interleaving
+	// the flags recorded in step 5 in argv order, such that the last wins
 	var (
 		countServers    int
 		countQueryTypes int
+		formatSources   []outputFormatSource
+		noall           bool
+		used            = map[string]bool{}
 	)
-	for _, arg := range positional {
+	applyOrdered := func(pos int) error {
+		for _, flag := range ordered {
+			if flag.Pos != pos {
+				continue
+			}
+			switch flag.Name {
+			case "answers-only":
+				if enabled, _ := strconv.ParseBool(flag.Value); enabled {
+					formatSources = append(formatSources, outputFormatSource{Arg: "--answers-only", Format: outputFormatAnswers})
+				}
+
+			case "csv":
+				if enabled, _ := strconv.ParseBool(flag.Value); enabled {
+					formatSources = append(formatSources, outputFormatSource{Arg: "--csv", Format: outputFormatCSV})
+				}
+
+			case "output-format":
+				if !outputFormatNames[flag.Value] {
+					return fmt.Errorf("unsupported output format: %s", flag.Value)
+				}
+				formatSources = append(formatSources, outputFormatSource{
+					Arg:    "--output-format=" + flag.Value,
+					Format: flag.Value,
+				})
+
+			case "protocol":
+				if err := task.setProtocol(flag.Value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for idx, arg := range positional {
+
+		// 8.0. apply the flags preceding this argument
+		if err := applyOrdered(idx); err != nil {
+			return failUsage(env, err)
+		}
 
-		// 7.1. parse the server name using the "@" syntax like in dig
+		// 8.1. parse the server name using the "@" syntax like in dig
 		if strings.HasPrefix(arg, "@") {
 			countServers++
+			if *compare {
+				task.CompareServers = append(task.CompareServers, arg[1:])
+				continue
+			}
+			if *parallel {
+				task.ParallelServers = append(task.ParallelServers, arg[1:])
+				continue
+			}
 			if countServers > 1 {
 				fmt.Fprintf(env.Stderr(), "rbmk dig: warning: you specified more than one server to query\n")
 				// fallthrough
@@ -100,24 +246,50 @@ func (cmd command) Main(ctx context.Context, env cliutils.Environment, argv ...s
 			continue
 		}
 
-		// 7.2. parse the query options using the "+" syntax like in dig
+		// 8.2. parse the query options using the "+" syntax like in dig
 		if strings.HasPrefix(arg, "+") {
+			name, _, _ := strings.Cut(arg, "=")
+			used[name] = true
 			switch {
+			case arg == "+answer":
+				// like dig, +answer only matters after +noall
+				if noall {
+					formatSources = append(formatSources, outputFormatSource{Arg: arg, Format: outputFormatAnswers})
+				}
+				continue
+
+			case strings.HasPrefix(arg, "+bufsize="):
+				value, err := strconv.ParseUint(strings.TrimPrefix(arg, "+bufsize="), 10, 16)
+				if err != nil || value <= 0 {
+					return failUsage(env, fmt.Errorf("invalid EDNS0 buffer size: %s", arg))
+				}
+				task.EDNSBufferSize = uint16(value)
+				continue
+
+			case arg == "+cdflag":
+				task.CheckingDisabled = true
+				continue
+
 			case arg == "+https":
 				task.Protocol = "doh"
 				task.ServerPort = "443"
 				task.WaitDuplicates = false
 				continue
 
+			case arg == "+keepalive":
+				task.TCPKeepalive = true
+				continue
+
 			case arg == "+logs":
 				task.LogsWriter = env.Stdout()
 				continue
 
 			case arg == "+noall":
+				task.DiffWriter = io.Discard
 				task.LogsWriter = io.Discard
 				task.QueryWriter = io.Discard
-				task.ResponseWriter = io.Discard
-				task.ShortWriter = io.Discard
+				formatSources = append(formatSources, outputFormatSource{Arg: arg, Format: outputFormatNone})
+				noall = true
 				continue
 
 			case arg == "+qr":
@@ -125,9 +297,15 @@ func (cmd command) Main(ctx context.Context, env cliutils.Environment, argv ...s
 				continue
 
 			case arg == "+short" || arg == "+short=ip":
-				task.ResponseWriter = io.Discard
-				task.ShortWriter = env.Stdout()
-				task.ShortIP = arg == "+short=ip"
+				formatSources = append(formatSources, outputFormatSource{Arg: arg, Format: arg[1:]})
+				continue
+
+			case strings.HasPrefix(arg, "+subnet="):
+				prefix, err := parseClientSubnet(strings.TrimPrefix(arg, "+subnet="))
+				if err != nil {
+					return failUsage(env, err)
+				}
+				task.ClientSubnet = prefix
 				continue
 
 			case arg == "+tcp":
@@ -149,14 +327,11 @@ func (cmd command) Main(ctx context.Context, env cliutils.Environment, argv ...s
 				continue
 
 			default:
-				err := fmt.Errorf("unknown positonal argument: %s", arg)
-				fmt.Fprintf(env.Stderr(), "rbmk dig: %s\n", err.Error())
-				fmt.Fprintf(env.Stderr(), "Run `rbmk dig --help` for usage.\n")
-				return err
+				return failUsage(env, fmt.Errorf("unknown positonal argument: %s", arg))
 			}
 		}
 
-		// 7.3. recognise the query type
+		// 8.3. recognise the query type
 		if _, ok := queryTypeMap[arg]; ok {
 			countQueryTypes++
 			if countQueryTypes > 1 {
@@ -167,23 +342,237 @@ func (cmd command) Main(ctx context.Context, env cliutils.Environment, argv ...s
 			continue
 		}
 
-		// 7.4. recognise the name to resolve
+		// 8.4. recognise the name to resolve
 		if task.Name == "" {
 			task.Name = arg
 			continue
 		}
 
-		// 7.5. everything else is a command line error
-		err := fmt.Errorf("too many positional arguments: %s", arg)
-		fmt.Fprintf(env.Stderr(), "rbmk dig: %s\n", err.Error())
-		fmt.Fprintf(env.Stderr(), "Run `rbmk dig --help` for usage.\n")
-		return err
+		// 8.5. everything else is a command line error
+		return failUsage(env, fmt.Errorf("too many positional arguments: %s", arg))
+	}
+	if err := applyOrdered(len(positional)); err != nil {
+		return failUsage(env, err)
+	}
+
+	// 8.6. resolve the output format once from all its sources
+	format, discard, err := resolveOutputFormat(formatSources)
+	if err != nil {
+		return failUsage(env, err)
+	}
+	stdout := env.Stdout()
+	if discard {
+		stdout = io.Discard
+	}
+	if err := task.setOutputFormat(format, stdout); err != nil {
+		return failUsage(env, err)
+	}
+
+	// 8.7. select the run mode, rejecting the options it does not honour
+	clip.Visit(func(flag *pflag.Flag) {
+		used["--"+flag.Name] = true
+	})
+	if _, err := selectRunMode(used); err != nil {
+		return failUsage(env, err)
 	}
 	if task.Name == "" {
 		task.Name = "www.example.com."
 	}
+	if *compare && len(task.CompareServers) < 2 {
+		return failUsage(env, errors.New("--compare requires at least two @SERVER arguments"))
+	}
+	if *parallel && len(task.ParallelServers) < 2 {
+		return failUsage(env, errors.New("--servers-parallel requires at least two @SERVER arguments"))
+	}
+	if !strings.HasPrefix(*dohPath, "/") {
+		return failUsage(env, fmt.Errorf("--doh-path must begin with /: %s", *dohPath))
+	}
+	if *dohHost != "" && (task.Protocol == "tcp" || task.Protocol == "dot") {
+		return failUsage(env, errors.New("--doh-host conflicts with +tcp and +tls"))
+	}
+	if *dohHost != "" && *resolvconf {
+		return failUsage(env, errors.New("--doh-host conflicts with --server-from-resolv-conf"))
+	}
+	if *tcpKeepalive != 0 {
+		switch {
+		case *tcpKeepalive < 0:
+			return failUsage(env, errors.New("--tcp-keepalive requires a positive value"))
+		case task.Protocol != "tcp" && task.Protocol != "dot":
+			return failUsage(env, errors.New("--tcp-keepalive requires +tcp or +tls"))
+		}
+	}
+	if *tcpMSS != 0 {
+		switch {
+		case !tcpMSSSupported:
+			return failUsage(env, errors.New("--tcp-mss is only supported on Linux"))
+		case *tcpMSS < 0:
+			return failUsage(env, errors.New("--tcp-mss requires a positive value"))
+		case task.Protocol != "tcp" && task.Protocol != "dot":
+			return failUsage(env, errors.New("--tcp-mss requires +tcp or +tls"))
+		}
+	}
+	if *repeatChange != 0 && (*repeatChange < 0 || *repeatMax < 1) {
+		return failUsage(env, errors.New("--repeat-until-change requires a positive interval and --repeat-max"))
+	}
+	if (*untilNoError || *untilNXDomain) && (*pollInterval <= 0 || *pollTimeout <= 0) {
+		return failUsage(env, errors.New("--poll-interval and --poll-timeout require positive values"))
+	}
+	for _, name := range *retryProtos {
+		if _, err := parseProtocol(name); err != nil {
+			return failUsage(env, err)
+		}
+	}
+	if *waitAllDups {
+		if task.Protocol != "udp" {
+			return failUsage(env, errors.New("--wait-all-duplicates conflicts with +tcp, +tls, and +https"))
+		}
+		task.WaitDuplicates = true
+	}
+	if *duptimeout != 0 && (*duptimeout < 0 || !task.WaitDuplicates) {
+		return failUsage(env, errors.New("--duplicates-timeout requires a positive value and +udp=wait-duplicates or --wait-all-duplicates"))
+	}
+	if *bindiface != "" && !bindInterfaceSupported {
+		return failUsage(env, errors.New("--bind-interface is only supported on Linux"))
+	}
+	if *resolvconf && countServers > 0 {
+		return failUsage(env, errors.New("--server-from-resolv-conf conflicts with @SERVER"))
+	}
+
+	// 9. honour the flags modifying the task
+	task.ALPN = *alpn
+	task.BindInterface = *bindiface
+	task.CampaignID = *campaignID
+	task.DuplicatesTimeout = *duptimeout
+	task.FailOnBogon = *failOnBogon
+	task.FailOnEmpty = *failOnEmpty
+	task.MinTTL = *minTTL
+	task.Insecure = *insecure
+	if *hexdump {
+		task.HexDumpWriter = env.Stderr()
+	}
+	task.NoRecursion = *norecurse || *stub
+	task.NoValidate = *novalidate
+	task.RetryProtocols = *retryProtos
+	if task.NoRecursion && *recursive {
+		return failUsage(env, errors.New("--recursive conflicts with --norecurse and --stub"))
+	}
+	if *deadline != "" {
+		value, err := parseDeadline(*deadline, time.Now())
+		if err != nil {
+			return failUsage(env, err)
+		}
+		task.Deadline = value
+	}
+	if *ednsflags != "" {
+		flags, err := parseEDNSFlags(*ednsflags)
+		if err != nil {
+			return failUsage(env, err)
+		}
+		task.EDNSFlags = flags
+	}
+	if clip.Changed("count-answers") {
+		bounds, err := parseCountAnswers(*countAnswers)
+		if err != nil {
+			return failUsage(env, err)
+		}
+		task.CountAnswers = bounds
+	}
+	if len(*expect) > 0 {
+		addrs, err := parseExpect(*expect)
+		if err != nil {
+			return failUsage(env, err)
+		}
+		task.Expect = addrs
+	}
+	if *dohHost != "" {
+		task.BootstrapServer = task.ServerAddr
+		task.Protocol = "doh"
+		task.ServerAddr = *dohHost
+		task.ServerPort = "443"
+		task.WaitDuplicates = false
+	}
+	if *repeatChange > 0 {
+		task.RepeatInterval = *repeatChange
+		task.RepeatMax = *repeatMax
+	}
+	task.SummaryFile = *summaryfile
+	if *tracePhases {
+		task.PhasesWriter = env.Stdout()
+	}
+	if *untilNoError || *untilNXDomain {
+		task.PollInterval = *pollInterval
+		task.PollTimeout = *pollTimeout
+		task.PollUntil = "noerror"
+		if *untilNXDomain {
+			task.PollUntil = "nxdomain"
+		}
+	}
+	task.TCPFallback = *tcpFallback
+	task.TCPMSS = *tcpMSS
+	task.URLPath = *dohPath
+	if *tcpKeepalive > 0 {
+		task.KeepaliveIdle = *tcpKeepalive
+		task.TCPKeepalive = true
+	}
+	if *printQueryID {
+		task.QueryIDWriter = env.Stderr()
+	}
+	if *queryID != "" {
+		value, err := strconv.ParseUint(*queryID, 10, 16)
+		if err != nil {
+			return failUsage(env, fmt.Errorf("invalid query ID: %s", *queryID))
+		}
+		id := uint16(value)
+		task.QueryID = &id
+	}
+	if *resolvconf {
+		servers, err := loadResolvConf(env.FS(), resolvConfPath)
+		if err != nil {
+			fmt.Fprintf(env.Stderr(), "rbmk dig: warning: %s\n", err.Error())
+			fmt.Fprintf(env.Stderr(), "rbmk dig: warning: falling back to %s\n", task.ServerAddr)
+			// fallthrough
+		}
+		task.Servers = servers
+	}
+	if *certdump != "" {
+		if err := env.FS().MkdirAll(*certdump, 0700); err != nil {
+			err = fmt.Errorf("cannot create cert dump dir: %w", err)
+			fmt.Fprintf(env.Stderr(), "rbmk dig: %s\n", err.Error())
+			return err
+		}
+		task.CertDumpDir = *certdump
+	}
+	if *outputdir != "" {
+		if err := env.FS().MkdirAll(*outputdir, 0700); err != nil {
+			err = fmt.Errorf("cannot create output dir: %w", err)
+			fmt.Fprintf(env.Stderr(), "rbmk dig: %s\n", err.Error())
+			return err
+		}
+		task.OutputDir = *outputdir
+	}
+	if *rawquery != "" {
+		data, err := loadRawQuery(env.FS(), *rawquery)
+		if err != nil {
+			err = fmt.Errorf("cannot load raw query: %w", err)
+			fmt.Fprintf(env.Stderr(), "rbmk dig: %s\n", err.Error())
+			return err
+		}
+		task.RawQuery = data
+	}
+	if *caSystem && len(*cafiles) <= 0 {
+		return failUsage(env, errors.New("--ca-system-roots requires --ca-file"))
+	}
+	if len(*cafiles) > 0 {
+		pool, err := loadRootCAs(env.FS(), *caSystem, *cafiles...)
+		if err != nil {
+			err = fmt.Errorf("cannot load root CAs: %w", err)
+			fmt.Fprintf(env.Stderr(), "rbmk dig: %s\n", err.Error())
+			return err
+		}
+		task.RootCAs = pool
+	}
 
-	// 8. possibly open the log file
+	// 10. possibly open the log file
 	var filepool closepool.Pool
 	switch *logfile {
 	case "":
@@ -202,24 +591,33 @@ func (cmd command) Main(ctx context.Context, env cliutils.Environment, argv ...s
 		task.LogsWriter = io.MultiWriter(task.LogsWriter, filep)
 	}
 
-	// 9. run the task and honour the `--measure` flag
-	err := task.Run(ctx)
-	if err != nil && *measure {
+	// 11. run the task and honour the `--measure` flag, except for failed
+	// expectations and policy violations, which are explicit assertions
+	err = task.Run(ctx)
+	if err != nil && *measure && !errors.Is(err, errExpectationFailed) && !errors.Is(err, errPolicyViolation) {
 		fmt.Fprintf(env.Stderr(), "rbmk dig: %s\n", err.Error())
 		fmt.Fprintf(env.Stderr(), "rbmk dig: not failing because you specified --measure\n")
 		err = nil
 	}
 
-	// 10. ensure we close the opened files
+	// 12. ensure we close the opened files
 	if err2 := filepool.Close(); err2 != nil {
 		fmt.Fprintf(env.Stderr(), "rbmk dig: %s\n", err2.Error())
 		return err2
 	}
 
-	// 11. handle error when running the task
+	// 13. handle error when running the task
 	if err != nil {
 		fmt.Fprintf(env.Stderr(), "rbmk dig: %s\n", err.Error())
 		return err
 	}
 	return nil
 }
+
+// failUsage prints the given command line error along with a hint
+// to read the help to the stderr and returns the error.
+func failUsage(env cliutils.Environment, err error) error {
+	fmt.Fprintf(env.Stderr(), "rbmk dig: %s\n", err.Error())
+	fmt.Fprintf(env.Stderr(), "Run `rbmk dig --help` for usage.\n")
+	return err
+}
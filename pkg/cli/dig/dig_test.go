@@ -4,9 +4,16 @@ package dig
 
 import (
 	"context"
+	"errors"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/rbmk-project/common/cliutils"
+	"github.com/rbmk-project/rbmk/internal/testable"
 )
 
 func TestCommand(t *testing.T) {
@@ -27,4 +34,168 @@ func TestCommand(t *testing.T) {
 			t.Fatalf("expected 'not implemented', got %v", err)
 		}
 	})
+
+	t.Run("conflicting recursion flags", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--recursive", "--stub", "www.example.com")
+		if err == nil || err.Error() != "--recursive conflicts with --norecurse and --stub" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("deadline in the past", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--deadline", "2000-01-01T00:00:00Z", "www.example.com")
+		if err == nil || !strings.HasPrefix(err.Error(), "--deadline is in the past") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("resolv.conf servers conflicting with @SERVER", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--server-from-resolv-conf", "@8.8.8.8", "www.example.com")
+		if err == nil || err.Error() != "--server-from-resolv-conf conflicts with @SERVER" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("invalid EDNS0 buffer size", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "+bufsize=65536", "www.example.com")
+		if err == nil || err.Error() != "invalid EDNS0 buffer size: +bufsize=65536" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("DoH path not beginning with a slash", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--doh-path", "dns-query", "www.example.com")
+		if err == nil || err.Error() != "--doh-path must begin with /: dns-query" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("polling until both NOERROR and NXDOMAIN", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--until-noerror", "--until-nxdomain", "www.example.com")
+		if err == nil || err.Error() != "--until-noerror conflicts with --until-nxdomain" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("TCP MSS without TCP", func(t *testing.T) {
+		if !tcpMSSSupported {
+			t.Skip("setting the TCP MSS is only supported on Linux")
+		}
+		err := cmd.Main(context.Background(), stdenv, "dig", "--tcp-mss", "536", "+udp", "www.example.com")
+		if err == nil || err.Error() != "--tcp-mss requires +tcp or +tls" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("unsupported retry protocol", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--retry-protocols", "udp,doq", "www.example.com")
+		if err == nil || err.Error() != "unsupported protocol: doq" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("duplicates timeout without waiting for duplicates", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--duplicates-timeout", "1s", "www.example.com")
+		if err == nil || !strings.HasPrefix(err.Error(), "--duplicates-timeout requires") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("waiting for all duplicates using TCP", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--wait-all-duplicates", "+tcp", "www.example.com")
+		if err == nil || err.Error() != "--wait-all-duplicates conflicts with +tcp, +tls, and +https" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("query ID not fitting in 16 bits", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--query-id", "65536", "www.example.com")
+		if err == nil || err.Error() != "invalid query ID: 65536" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("probing keepalive using UDP", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--tcp-keepalive", "1s", "www.example.com")
+		if err == nil || err.Error() != "--tcp-keepalive requires +tcp or +tls" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("option not honoured by the run mode", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--compare", "--fail-on-bogon", "@8.8.8.8", "@1.1.1.1", "www.example.com")
+		if err == nil || err.Error() != "--fail-on-bogon conflicts with --compare" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("--count-answers with a bare zero", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--count-answers", "0", "www.example.com")
+		if err == nil || err.Error() != `invalid --count-answers range: "0" (use 0:0 to require no answers)` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("+ option not honoured by the run mode", func(t *testing.T) {
+		err := cmd.Main(context.Background(), stdenv, "dig", "--retry-protocols", "udp,tcp", "+tcp", "www.example.com")
+		if err == nil || err.Error() != "+tcp conflicts with --retry-protocols" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("--protocol and the + options in argv order", func(t *testing.T) {
+		// we record the dialed addresses rather than actually connecting
+		var (
+			dialed []string
+			mu     sync.Mutex
+		)
+		testable.DialContext.Set(func(ctx context.Context, network, address string) (net.Conn, error) {
+			mu.Lock()
+			dialed = append(dialed, network+"/"+address)
+			mu.Unlock()
+			return nil, errors.New("mocked dial error")
+		})
+		defer testable.DialContext.Set(nil)
+
+		cases := []struct {
+			argv   []string
+			expect string
+		}{
+			{argv: []string{"--protocol", "tcp", "+tls"}, expect: "tcp/127.0.0.1:853"},
+			{argv: []string{"+tls", "--protocol", "tcp"}, expect: "tcp/127.0.0.1:53"},
+			{argv: []string{"--protocol=tcp", "+udp"}, expect: "udp/127.0.0.1:53"},
+			{argv: []string{"+https", "--protocol=dot"}, expect: "tcp/127.0.0.1:853"},
+		}
+		for _, tc := range cases {
+			dialed = nil
+			argv := append([]string{"dig", "@127.0.0.1"}, tc.argv...)
+			argv = append(argv, "www.example.com")
+			if err := cmd.Main(context.Background(), stdenv, argv...); err == nil {
+				t.Fatal("expected an error")
+			}
+			if len(dialed) != 1 || dialed[0] != tc.expect {
+				t.Fatalf("%v: expected to dial %s, got %v", tc.argv, tc.expect, dialed)
+			}
+		}
+	})
+
+	t.Run("--tcp-mss does not bypass the dial hook", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("--tcp-mss is only supported on Linux")
+		}
+		var dialed atomic.Int64
+		testable.DialContext.Set(func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed.Add(1)
+			return nil, errors.New("mocked dial error")
+		})
+		defer testable.DialContext.Set(nil)
+
+		argv := []string{"dig", "@127.0.0.1", "+tcp", "--tcp-mss", "536", "www.example.com"}
+		if err := cmd.Main(context.Background(), stdenv, argv...); err == nil {
+			t.Fatal("expected an error")
+		}
+		if dialed.Load() != 1 {
+			t.Fatalf("expected to dial once, got %d", dialed.Load())
+		}
+	})
 }
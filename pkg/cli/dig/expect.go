@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// errExpectationFailed indicates that the response does not contain
+// all the addresses listed in the Expect field. Because this error is
+// an explicit assertion, we fail even when using `--measure`.
+var errExpectationFailed = errors.New("expectation failed")
+
+// parseExpect parses the given list of IP addresses.
+func parseExpect(values []string) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	for _, value := range values {
+		addr, err := netip.ParseAddr(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --expect address: %q", value)
+		}
+		addrs = append(addrs, addr.Unmap())
+	}
+	return addrs, nil
+}
+
+// checkExpect checks whether the given response, which may be nil when
+// the query failed, contains all the addresses listed in the Expect field.
+//
+// On failure, we emit a dnsExpectationFailed structured log event and
+// return an error wrapping [errExpectationFailed].
+func (task *Task) checkExpect(ctx context.Context, logger *slog.Logger, resp *dns.Msg) error {
+	if len(task.Expect) <= 0 {
+		return nil
+	}
+	var got []netip.Addr
+	if resp != nil {
+		got = answerAddrs(resp)
+	}
+	var missing []string
+	for _, addr := range task.Expect {
+		if !slices.Contains(got, addr) {
+			missing = append(missing, addr.String())
+		}
+	}
+	if len(missing) <= 0 {
+		return nil
+	}
+	logger.InfoContext(
+		ctx,
+		"dnsExpectationFailed",
+		slog.Any("dnsExpectedAddrs", task.Expect),
+		slog.Any("dnsMissingAddrs", missing),
+		slog.Time("t", time.Now()),
+	)
+	return fmt.Errorf("%w: missing %s", errExpectationFailed, strings.Join(missing, ", "))
+}
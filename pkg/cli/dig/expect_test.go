@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseExpect(t *testing.T) {
+	t.Run("with valid addresses", func(t *testing.T) {
+		addrs, err := parseExpect([]string{"93.184.216.34", " 2001:db8::1", "::ffff:10.0.0.1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := []netip.Addr{
+			netip.MustParseAddr("93.184.216.34"),
+			netip.MustParseAddr("2001:db8::1"),
+			netip.MustParseAddr("10.0.0.1"),
+		}
+		if len(addrs) != len(expect) {
+			t.Fatalf("unexpected addresses: %v", addrs)
+		}
+		for idx := range expect {
+			if addrs[idx] != expect[idx] {
+				t.Fatalf("expected %s, got %s", expect[idx], addrs[idx])
+			}
+		}
+	})
+
+	t.Run("with an invalid address", func(t *testing.T) {
+		_, err := parseExpect([]string{"93.184.216.34", "www.example.com"})
+		if err == nil || err.Error() != `invalid --expect address: "www.example.com"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestTaskCheckExpect(t *testing.T) {
+	resp := &dns.Msg{}
+	resp.Answer = []dns.RR{newTestA("93.184.216.34"), newTestA("93.184.216.35")}
+
+	newTask := func(addrs ...string) *Task {
+		task := newTestTask()
+		for _, addr := range addrs {
+			task.Expect = append(task.Expect, netip.MustParseAddr(addr))
+		}
+		return task
+	}
+
+	t.Run("when the response matches", func(t *testing.T) {
+		var logs strings.Builder
+		task := newTask("93.184.216.35", "93.184.216.34")
+		if err := task.checkExpect(context.Background(), newTestLogger(&logs), resp); err != nil {
+			t.Fatal(err)
+		}
+		if logs.Len() != 0 {
+			t.Fatalf("unexpected logs: %q", logs.String())
+		}
+	})
+
+	t.Run("when the response partially matches", func(t *testing.T) {
+		var logs strings.Builder
+		task := newTask("93.184.216.34", "93.184.216.36")
+		err := task.checkExpect(context.Background(), newTestLogger(&logs), resp)
+		if !errors.Is(err, errExpectationFailed) || err.Error() != "expectation failed: missing 93.184.216.36" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(logs.String(), `"msg":"dnsExpectationFailed"`) ||
+			!strings.Contains(logs.String(), `"dnsMissingAddrs":["93.184.216.36"]`) {
+			t.Fatalf("unexpected logs: %q", logs.String())
+		}
+	})
+
+	t.Run("when the response misses the expectation", func(t *testing.T) {
+		task := newTask("10.0.0.1", "10.0.0.2")
+		err := task.checkExpect(context.Background(), newTestLogger(io.Discard), resp)
+		if !errors.Is(err, errExpectationFailed) || err.Error() != "expectation failed: missing 10.0.0.1, 10.0.0.2" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("when there is no response", func(t *testing.T) {
+		task := newTask("93.184.216.34")
+		err := task.checkExpect(context.Background(), newTestLogger(io.Discard), nil)
+		if !errors.Is(err, errExpectationFailed) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("without expectations", func(t *testing.T) {
+		task := newTask()
+		if err := task.checkExpect(context.Background(), newTestLogger(io.Discard), nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
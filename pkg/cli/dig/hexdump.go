@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// hexDumpLabels maps the structured log events carrying raw DNS
+// messages to the attribute containing the bytes we should dump
+// and the label we use when writing the hex dump.
+var hexDumpLabels = map[string]struct{ key, label string }{
+	"dnsQuery":    {key: "dnsRawQuery", label: "Query"},
+	"dnsResponse": {key: "dnsRawResponse", label: "Response"},
+}
+
+// writeHexDump writes a [hex.Dump] of the given raw DNS message
+// to the given [io.Writer] using the given label.
+func writeHexDump(w io.Writer, label string, rawMsg []byte) {
+	fmt.Fprintf(w, ";; %s (%d bytes):\n%s\n", label, len(rawMsg), hex.Dump(rawMsg))
+}
+
+// hexDumper writes a hex dump of the raw query and response bytes
+// included in the dnsQuery and dnsResponse structured log events,
+// such that we dump the bytes actually sent and received rather
+// than the bytes we would obtain by re-packing the parsed messages.
+type hexDumper struct {
+	// mu serializes writes, since queries may run concurrently.
+	mu sync.Mutex
+
+	// w is where to write the hex dumps.
+	w io.Writer
+}
+
+// wrap returns a [slog.Handler] that writes the hex dumps
+// and forwards each record to the given [slog.Handler].
+func (hd *hexDumper) wrap(handler slog.Handler) slog.Handler {
+	return &hexDumpHandler{handler: handler, dumper: hd}
+}
+
+// add writes the hex dump of the raw message in the given record, if any.
+//
+// Because the events of concurrent queries may interleave, we include the
+// server address and protocol in the label, when the record contains them.
+func (hd *hexDumper) add(record slog.Record) {
+	info, ok := hexDumpLabels[record.Message]
+	if !ok {
+		return
+	}
+	var (
+		rawMsg         []byte
+		serverAddr     string
+		serverProtocol string
+	)
+	record.Attrs(func(attr slog.Attr) bool {
+		switch attr.Key {
+		case info.key:
+			rawMsg, _ = attr.Value.Any().([]byte)
+		case "serverAddr":
+			serverAddr = attr.Value.String()
+		case "serverProtocol":
+			serverProtocol = attr.Value.String()
+		}
+		return true
+	})
+	if rawMsg == nil {
+		return
+	}
+	label := info.label
+	if serverAddr != "" {
+		label = fmt.Sprintf("%s %s/%s", label, serverProtocol, serverAddr)
+	}
+	hd.mu.Lock()
+	writeHexDump(hd.w, label, rawMsg)
+	hd.mu.Unlock()
+}
+
+// hexDumpHandler is the [slog.Handler] returned by [*hexDumper.wrap].
+type hexDumpHandler struct {
+	handler slog.Handler
+	dumper  *hexDumper
+}
+
+var _ slog.Handler = &hexDumpHandler{}
+
+// Enabled implements [slog.Handler].
+func (hh *hexDumpHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return hh.handler.Enabled(ctx, level)
+}
+
+// Handle implements [slog.Handler].
+func (hh *hexDumpHandler) Handle(ctx context.Context, record slog.Record) error {
+	hh.dumper.add(record)
+	return hh.handler.Handle(ctx, record)
+}
+
+// WithAttrs implements [slog.Handler].
+func (hh *hexDumpHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &hexDumpHandler{handler: hh.handler.WithAttrs(attrs), dumper: hh.dumper}
+}
+
+// WithGroup implements [slog.Handler].
+func (hh *hexDumpHandler) WithGroup(name string) slog.Handler {
+	return &hexDumpHandler{handler: hh.handler.WithGroup(name), dumper: hh.dumper}
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestWriteHexDump(t *testing.T) {
+	msg := new(dns.Msg)
+	msg.SetQuestion("example.com.", dns.TypeA)
+	msg.Id = 0x1234
+	rawMsg, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	writeHexDump(&sb, "Query", rawMsg)
+	output := sb.String()
+
+	// The header must contain the ID, the flags with RD set, and QDCOUNT = 1
+	if !strings.HasPrefix(output, ";; Query (29 bytes):\n") {
+		t.Fatalf("unexpected label: %q", output)
+	}
+	if !strings.Contains(output, "00000000  12 34 01 00 00 01 00 00  00 00 00 00") {
+		t.Fatalf("missing header bytes: %q", output)
+	}
+}
+
+func TestHexDumper(t *testing.T) {
+	var sb strings.Builder
+	dumper := &hexDumper{w: &sb}
+	logger := slog.New(dumper.wrap(newTestLogger(io.Discard).Handler()))
+
+	// We dump the raw bytes as they are, even when they are not what we
+	// would obtain by packing the message (e.g., a truncated response)
+	rawQuery := []byte{0x12, 0x34, 0x01, 0x00}
+	rawResp := []byte{0xab, 0xcd, 0x81, 0x80, 0x00, 0x01, 0x00}
+	logger.InfoContext(context.Background(), "dnsQuery",
+		slog.Any("dnsRawQuery", rawQuery),
+		slog.String("serverAddr", "8.8.8.8:53"),
+		slog.String("serverProtocol", "udp"),
+	)
+	logger.InfoContext(context.Background(), "dnsResponse",
+		slog.Any("dnsRawQuery", rawQuery),
+		slog.Any("dnsRawResponse", rawResp),
+		slog.String("serverAddr", "8.8.8.8:53"),
+		slog.String("serverProtocol", "udp"),
+	)
+	logger.InfoContext(context.Background(), "connectDone")
+
+	output := sb.String()
+	for _, expect := range []string{
+		";; Query udp/8.8.8.8:53 (4 bytes):\n00000000  12 34 01 00",
+		";; Response udp/8.8.8.8:53 (7 bytes):\n00000000  ab cd 81 80 00 01 00",
+	} {
+		if !strings.Contains(output, expect) {
+			t.Fatalf("expected %q in %q", expect, output)
+		}
+	}
+	if count := strings.Count(output, ";; "); count != 2 {
+		t.Fatalf("expected two hex dumps, got %d", count)
+	}
+}
@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// probeKeepalive sends the query over a TCP or DoT connection created using
+// the given dial function, waits for the response, and then keeps the connection
+// idle for KeepaliveIdle to check whether the server closes it, comparing the
+// time of closure with the keepalive timeout advertised by the server, if any.
+//
+// Since we bypass the [dnscore.Transport] to keep using the connection, we
+// emit ourselves the dnsQuery and dnsResponse structured log events it would
+// emit (see logRawQuery and logRawResponse). We also emit a dnsKeepaliveProbe
+// structured log event describing the outcome.
+func (task *Task) probeKeepalive(
+	ctx context.Context,
+	logger *slog.Logger,
+	dial func(ctx context.Context, network, address string) (net.Conn, error),
+	protocol dnscore.Protocol,
+	query *dns.Msg,
+) (*dns.Msg, error) {
+	// Send the query and read the response
+	address := task.newServerAddr(protocol, task.ServerAddr)
+	server := dnscore.NewServerAddr(protocol, address)
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	rawQuery, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	queryT0 := logRawQuery(ctx, logger, server, rawQuery)
+	frame := binary.BigEndian.AppendUint16(nil, uint16(len(rawQuery)))
+	if _, err := conn.Write(append(frame, rawQuery...)); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	rawResp := make([]byte, binary.BigEndian.Uint16(header))
+	if _, err := io.ReadFull(conn, rawResp); err != nil {
+		return nil, err
+	}
+	logRawResponse(ctx, logger, server, queryT0, rawQuery, rawResp, conn.LocalAddr(), conn.RemoteAddr())
+	resp := &dns.Msg{}
+	if err := resp.Unpack(rawResp); err != nil {
+		return nil, err
+	}
+	task.streamResponse(server, resp, nil)
+
+	// Keep the connection idle and wait for the server to close it
+	advertised, hasAdvertised := responseTCPKeepalive(resp)
+	t0 := time.Now()
+	conn.SetReadDeadline(t0.Add(task.KeepaliveIdle))
+	_, err = conn.Read(make([]byte, 1))
+	t := time.Now()
+	closed := err != nil && !errors.Is(err, os.ErrDeadlineExceeded)
+	closedEarly := closed && hasAdvertised && t.Sub(t0) < advertised
+	if !closed {
+		err = nil
+	}
+	logger.InfoContext(
+		ctx,
+		"dnsKeepaliveProbe",
+		slog.Any("err", err),
+		slog.Int64("keepaliveAdvertisedMs", advertised.Milliseconds()),
+		slog.Int64("keepaliveIdleMs", task.KeepaliveIdle.Milliseconds()),
+		slog.Bool("serverClosed", closed),
+		slog.Bool("serverClosedEarly", closedEarly),
+		slog.String("serverAddr", address),
+		slog.String("serverProtocol", string(protocol)),
+		slog.Time("t0", t0),
+		slog.Time("t", t),
+	)
+
+	// Tell the user what happened
+	switch {
+	case closed:
+		fmt.Fprintf(task.ResponseWriter, ";; Server closed the idle connection after %s\n", t.Sub(t0).Round(time.Millisecond))
+	default:
+		fmt.Fprintf(task.ResponseWriter, ";; Connection still open after %s of idle time\n", task.KeepaliveIdle)
+	}
+	if closedEarly {
+		fmt.Fprintf(task.ResponseWriter, ";; WARNING: closed before the advertised keepalive timeout (%s)\n", advertised)
+	}
+	return resp, nil
+}
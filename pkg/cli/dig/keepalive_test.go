@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// newKeepaliveDialer returns a dial function connected to a mock DNS-over-TCP
+// server answering the first query while advertising the given keepalive timeout
+// and then closing the connection after closeDelay, unless it is zero.
+func newKeepaliveDialer(t *testing.T, advertised uint16, closeDelay time.Duration) func(
+	ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(server, header); err != nil {
+				return
+			}
+			rawQuery := make([]byte, binary.BigEndian.Uint16(header))
+			if _, err := io.ReadFull(server, rawQuery); err != nil {
+				return
+			}
+			query := &dns.Msg{}
+			if err := query.Unpack(rawQuery); err != nil {
+				return
+			}
+			resp := &dns.Msg{}
+			resp.SetReply(query)
+			resp.RecursionAvailable = true
+			resp.Answer = []dns.RR{newTestA("93.184.216.34")}
+			resp.SetEdns0(dnscore.EDNS0SuggestedMaxResponseSizeOtherwise, false)
+			resp.IsEdns0().Option = []dns.EDNS0{
+				&dns.EDNS0_TCP_KEEPALIVE{Code: dns.EDNS0TCPKEEPALIVE, Timeout: advertised},
+			}
+			rawResp, err := resp.Pack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			frame := binary.BigEndian.AppendUint16(nil, uint16(len(rawResp)))
+			if _, err := server.Write(append(frame, rawResp...)); err != nil {
+				return
+			}
+			if closeDelay > 0 {
+				time.Sleep(closeDelay)
+				return
+			}
+			<-ctx.Done()
+		}()
+		return client, nil
+	}
+}
+
+func TestTaskProbeKeepalive(t *testing.T) {
+	type probeEvent struct {
+		Msg               string `json:"msg"`
+		DNSRawResponse    []byte `json:"dnsRawResponse"`
+		ServerClosed      bool   `json:"serverClosed"`
+		ServerClosedEarly bool   `json:"serverClosedEarly"`
+		ServerProtocol    string `json:"serverProtocol"`
+	}
+
+	run := func(t *testing.T, advertised uint16, closeDelay, idle time.Duration) (*probeEvent, string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var logs, out strings.Builder
+		task := newTestTask()
+		task.KeepaliveIdle = idle
+		task.Protocol = "tcp"
+		task.ResponseWriter = &out
+		task.TCPKeepalive = true
+		query, err := task.newQuery(dnscore.ProtocolTCP, dns.TypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dial := newKeepaliveDialer(t, advertised, closeDelay)
+		resp, err := task.probeKeepalive(ctx, newTestLogger(&logs), dial, dnscore.ProtocolTCP, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Answer) != 1 {
+			t.Fatalf("unexpected response: %v", resp)
+		}
+		// We emit the same events that the dnscore transport would
+		// emit before the event describing the probe outcome
+		var events []probeEvent
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var ev probeEvent
+			if err := json.Unmarshal([]byte(line), &ev); err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, ev)
+		}
+		if len(events) != 3 || events[0].Msg != "dnsQuery" || events[1].Msg != "dnsResponse" ||
+			events[2].Msg != "dnsKeepaliveProbe" {
+			t.Fatalf("unexpected events: %+v", events)
+		}
+		if len(events[1].DNSRawResponse) <= 0 || events[1].ServerProtocol != "tcp" {
+			t.Fatalf("unexpected dnsResponse event: %+v", events[1])
+		}
+		return &events[2], out.String()
+	}
+
+	t.Run("when the server closes before the advertised timeout", func(t *testing.T) {
+		ev, out := run(t, 100, 10*time.Millisecond, time.Second) // 100 * 100ms = 10s
+		if !ev.ServerClosed || !ev.ServerClosedEarly {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+		if !strings.Contains(out, ";; WARNING: closed before the advertised keepalive timeout (10s)") {
+			t.Fatalf("unexpected output: %q", out)
+		}
+	})
+
+	t.Run("when the server keeps the connection open", func(t *testing.T) {
+		ev, out := run(t, 100, 0, 20*time.Millisecond)
+		if ev.ServerClosed || ev.ServerClosedEarly {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+		if !strings.Contains(out, ";; Connection still open after 20ms of idle time") {
+			t.Fatalf("unexpected output: %q", out)
+		}
+	})
+}
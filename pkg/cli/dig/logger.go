@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/rbmk-project/common/netipx"
+	"github.com/rbmk-project/dnscore"
+)
+
+// newLogger creates the JSON logger writing structured logs to the
+// LogsWriter. When CampaignID is set, we add to each record the campaign
+// ID and the runT0 time when the run started, which allows downstream
+// storage to group together the records emitted by each run.
+func (task *Task) newLogger(runT0 time.Time) *slog.Logger {
+	var handler slog.Handler = slog.NewJSONHandler(task.LogsWriter, &slog.HandlerOptions{})
+	if task.CampaignID != "" {
+		handler = handler.WithAttrs([]slog.Attr{
+			slog.String("campaignId", task.CampaignID),
+			slog.Time("runT0", runT0),
+		})
+	}
+	return slog.New(handler)
+}
+
+// logRawQuery emits a dnsQuery structured log event for the given raw
+// query sent to the given server, using the same schema as [dnscore.Transport],
+// and returns the time when we sent the query. We use this function when we
+// exchange raw messages without using the transport (e.g., --raw-query).
+func logRawQuery(ctx context.Context, logger *slog.Logger, addr *dnscore.ServerAddr, rawQuery []byte) time.Time {
+	t0 := time.Now()
+	logger.InfoContext(
+		ctx,
+		"dnsQuery",
+		slog.Any("dnsRawQuery", rawQuery),
+		slog.String("serverAddr", addr.Address),
+		slog.String("serverProtocol", string(addr.Protocol)),
+		slog.Time("t", t0),
+		slog.String("protocol", rawLogNetwork(addr.Protocol)),
+	)
+	return t0
+}
+
+// logRawResponse emits a dnsResponse structured log event for the given raw
+// response received from the given server, using the same schema as
+// [dnscore.Transport]. The local and remote addresses are nil when we
+// do not know them (e.g., with DoH), in which case we log them as
+// unspecified, like the transport does.
+func logRawResponse(ctx context.Context, logger *slog.Logger, addr *dnscore.ServerAddr,
+	t0 time.Time, rawQuery, rawResp []byte, laddr, raddr net.Addr) {
+	logger.InfoContext(
+		ctx,
+		"dnsResponse",
+		slog.String("localAddr", rawLogAddrPort(laddr).String()),
+		slog.Any("dnsRawQuery", rawQuery),
+		slog.Any("dnsRawResponse", rawResp),
+		slog.String("remoteAddr", rawLogAddrPort(raddr).String()),
+		slog.String("serverAddr", addr.Address),
+		slog.String("serverProtocol", string(addr.Protocol)),
+		slog.Time("t0", t0),
+		slog.Time("t", time.Now()),
+		slog.String("protocol", rawLogNetwork(addr.Protocol)),
+	)
+}
+
+// rawLogNetwork returns the network used by the given protocol.
+func rawLogNetwork(protocol dnscore.Protocol) string {
+	if protocol == dnscore.ProtocolUDP {
+		return "udp"
+	}
+	return "tcp"
+}
+
+// rawLogAddrPort converts the given [net.Addr] to a [netip.AddrPort],
+// using the unspecified address when it is nil or invalid.
+func rawLogAddrPort(addr net.Addr) netip.AddrPort {
+	var addrport netip.AddrPort
+	if addr != nil {
+		addrport = netipx.AddrToAddrPort(addr)
+	}
+	if !addrport.IsValid() {
+		addrport = netip.AddrPortFrom(netip.IPv6Unspecified(), 0)
+	}
+	return addrport
+}
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskNewLogger(t *testing.T) {
+	runT0 := time.Date(2024, 12, 25, 10, 0, 0, 0, time.UTC)
+
+	// record is the subset of the emitted record we care about
+	type record struct {
+		CampaignID string    `json:"campaignId"`
+		RunT0      time.Time `json:"runT0"`
+		T          time.Time `json:"t"`
+	}
+
+	// emit emits two records using a logger created by the task
+	emit := func(task *Task) []record {
+		var out strings.Builder
+		task.LogsWriter = &out
+		logger := task.newLogger(runT0)
+		logger.Info("first", "t", runT0.Add(time.Second))
+		logger.Info("second", "t", runT0.Add(2*time.Second))
+		var records []record
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			var rec record
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				t.Fatal(err)
+			}
+			records = append(records, rec)
+		}
+		return records
+	}
+
+	t.Run("with a campaign ID", func(t *testing.T) {
+		task := newTestTask()
+		task.CampaignID = "weekly-2024-52"
+		records := emit(task)
+		if len(records) != 2 {
+			t.Fatalf("expected two records, got %d", len(records))
+		}
+		for _, rec := range records {
+			if rec.CampaignID != "weekly-2024-52" || !rec.RunT0.Equal(runT0) {
+				t.Fatalf("unexpected record: %+v", rec)
+			}
+			if rec.T.Before(rec.RunT0) {
+				t.Fatalf("record time %s precedes the run start %s", rec.T, rec.RunT0)
+			}
+		}
+	})
+
+	t.Run("without a campaign ID", func(t *testing.T) {
+		for _, rec := range emit(newTestTask()) {
+			if rec.CampaignID != "" || !rec.RunT0.IsZero() {
+				t.Fatalf("unexpected record: %+v", rec)
+			}
+		}
+	})
+}
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// errTTLBelowMin indicates that the response contains an answer
+// whose TTL is below the threshold set using the MinTTL field.
+var errTTLBelowMin = errors.New("answer TTL below minimum")
+
+// lowestTTLAnswer returns the answer with the lowest TTL, if any.
+func lowestTTLAnswer(resp *dns.Msg) (dns.RR, bool) {
+	var lowest dns.RR
+	for _, ans := range resp.Answer {
+		if lowest == nil || ans.Header().Ttl < lowest.Header().Ttl {
+			lowest = ans
+		}
+	}
+	return lowest, lowest != nil
+}
+
+// checkMinTTL checks whether all the answers in the given response
+// have a TTL greater than or equal to the MinTTL field.
+//
+// On failure, we emit a dnsTTLBelowMin structured log event and
+// return an error wrapping [errTTLBelowMin].
+func (task *Task) checkMinTTL(ctx context.Context, logger *slog.Logger, resp *dns.Msg) error {
+	if task.MinTTL <= 0 {
+		return nil
+	}
+	ans, found := lowestTTLAnswer(resp)
+	if !found || ans.Header().Ttl >= task.MinTTL {
+		return nil
+	}
+	hdr := ans.Header()
+	logger.InfoContext(
+		ctx,
+		"dnsTTLBelowMin",
+		slog.String("dnsAnswerName", hdr.Name),
+		slog.String("dnsAnswerType", dns.TypeToString[hdr.Rrtype]),
+		slog.Uint64("dnsAnswerTtl", uint64(hdr.Ttl)),
+		slog.Uint64("dnsMinTtl", uint64(task.MinTTL)),
+		slog.Time("t", time.Now()),
+	)
+	return fmt.Errorf("%w: %s %s has TTL %d < %d", errTTLBelowMin,
+		hdr.Name, dns.TypeToString[hdr.Rrtype], hdr.Ttl, task.MinTTL)
+}
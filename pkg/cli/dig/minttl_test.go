@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestTaskCheckMinTTL(t *testing.T) {
+	newResponse := func(ttls ...uint32) *dns.Msg {
+		resp := &dns.Msg{}
+		resp.SetReply(newTestQuery(t))
+		for _, ttl := range ttls {
+			ans := newTestA("93.184.216.34")
+			ans.Header().Ttl = ttl
+			resp.Answer = append(resp.Answer, ans)
+		}
+		return resp
+	}
+
+	cases := []struct {
+		name   string
+		minTTL uint32
+		resp   *dns.Msg
+		expect error
+	}{
+		{name: "no threshold", minTTL: 0, resp: newResponse(1), expect: nil},
+		{name: "above the threshold", minTTL: 300, resp: newResponse(300, 3600), expect: nil},
+		{name: "below the threshold", minTTL: 300, resp: newResponse(3600, 60), expect: errTTLBelowMin},
+		{name: "without answers", minTTL: 300, resp: newResponse(), expect: nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs strings.Builder
+			task := newTestTask()
+			task.MinTTL = tc.minTTL
+			err := task.checkMinTTL(context.Background(), newTestLogger(&logs), tc.resp)
+			if !errors.Is(err, tc.expect) {
+				t.Fatalf("expected %v, got %v", tc.expect, err)
+			}
+			if tc.expect == nil {
+				if logs.Len() > 0 {
+					t.Fatalf("unexpected logs: %s", logs.String())
+				}
+				return
+			}
+			var ev struct {
+				Msg          string `json:"msg"`
+				DNSAnswerTTL uint32 `json:"dnsAnswerTtl"`
+				DNSMinTTL    uint32 `json:"dnsMinTtl"`
+			}
+			if err := json.Unmarshal([]byte(logs.String()), &ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Msg != "dnsTTLBelowMin" || ev.DNSAnswerTTL != 60 || ev.DNSMinTTL != 300 {
+				t.Fatalf("unexpected event: %+v", ev)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// reportTCPMSS writes to the ResponseWriter and logs using a dnsTcpMss
+// structured log event whether the response to the query sent using the
+// TCPMSS completed, which is useful to diagnose MSS and PMTU issues
+// that prevent large responses from reaching us.
+func (task *Task) reportTCPMSS(ctx context.Context, logger *slog.Logger, resp *dns.Msg, err error) {
+	if task.TCPMSS <= 0 {
+		return
+	}
+	var size int
+	if err == nil {
+		size = resp.Len()
+		fmt.Fprintf(task.ResponseWriter, ";; TCP MSS %d: response completed (%d bytes)\n\n", task.TCPMSS, size)
+	} else {
+		fmt.Fprintf(task.ResponseWriter, ";; TCP MSS %d: response did not complete: %s\n\n", task.TCPMSS, err.Error())
+	}
+	logger.InfoContext(
+		ctx,
+		"dnsTcpMss",
+		slog.Bool("completed", err == nil),
+		slog.Int("dnsResponseSize", size),
+		slog.Any("err", err),
+		slog.Int("tcpMss", task.TCPMSS),
+		slog.Time("t", time.Now()),
+	)
+}
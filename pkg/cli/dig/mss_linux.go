@@ -0,0 +1,35 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+//go:build linux
+
+package dig
+
+import (
+	"strings"
+	"syscall"
+)
+
+// tcpMSSSupported indicates whether we support --tcp-mss.
+const tcpMSSSupported = true
+
+// newTCPMSSControl returns a [net.Dialer] Control function that sets the
+// maximum segment size of each TCP socket using TCP_MAXSEG. Because the
+// Control function runs before connecting, the kernel advertises the
+// clamped MSS to the server in the SYN segment. We do not modify the
+// UDP sockets, for which the option is meaningless.
+func newTCPMSSControl(mss int) (func(network, address string, conn syscall.RawConn) error, error) {
+	control := func(network, address string, conn syscall.RawConn) error {
+		if !strings.HasPrefix(network, "tcp") {
+			return nil
+		}
+		var serr error
+		err := conn.Control(func(fd uintptr) {
+			serr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_MAXSEG, mss)
+		})
+		if err != nil {
+			return err
+		}
+		return serr
+	}
+	return control, nil
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+//go:build linux
+
+package dig
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+)
+
+// tcpMaxSeg returns the TCP_MAXSEG value of the given TCP connection.
+func tcpMaxSeg(t *testing.T, conn net.Conn) int {
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		value int
+		serr  error
+	)
+	if err := rawConn.Control(func(fd uintptr) {
+		value, serr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_MAXSEG)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if serr != nil {
+		t.Fatal(serr)
+	}
+	return value
+}
+
+func TestNewTCPMSSControl(t *testing.T) {
+	const mss = 536
+	control, err := newTCPMSSControl(mss)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("we clamp the MSS advertised during the handshake", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		dialer := &net.Dialer{Control: control}
+		conn, err := dialer.DialContext(context.Background(), "tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		serverConn, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer serverConn.Close()
+
+		// The server only learns our MSS from the SYN segment, so a clamped
+		// server-side MSS proves that we set the option before the handshake
+		if got := tcpMaxSeg(t, conn); got > mss {
+			t.Fatalf("expected client MSS <= %d, got %d", mss, got)
+		}
+		if got := tcpMaxSeg(t, serverConn); got > mss {
+			t.Fatalf("expected server MSS <= %d, got %d", mss, got)
+		}
+	})
+
+	t.Run("we do not modify UDP sockets", func(t *testing.T) {
+		dialer := &net.Dialer{Control: control}
+		conn, err := dialer.DialContext(context.Background(), "udp", "127.0.0.1:53")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	})
+}
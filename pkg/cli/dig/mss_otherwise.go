@@ -0,0 +1,19 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+//go:build !linux
+
+package dig
+
+import (
+	"errors"
+	"syscall"
+)
+
+// tcpMSSSupported indicates whether we support --tcp-mss.
+const tcpMSSSupported = false
+
+// newTCPMSSControl returns an error since we only support
+// setting the TCP maximum segment size on Linux.
+func newTCPMSSControl(mss int) (func(network, address string, conn syscall.RawConn) error, error) {
+	return nil, errors.New("setting the TCP maximum segment size is only supported on Linux")
+}
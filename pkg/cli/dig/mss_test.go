@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestTaskReportTCPMSS(t *testing.T) {
+	resp := &dns.Msg{}
+	resp.SetReply(newTestQuery(t))
+	resp.Answer = []dns.RR{newTestA("93.184.216.34")}
+
+	cases := []struct {
+		name   string
+		resp   *dns.Msg
+		err    error
+		expect string
+	}{{
+		name:   "when the response completed",
+		resp:   resp,
+		err:    nil,
+		expect: ";; TCP MSS 536: response completed (",
+	}, {
+		name:   "when the response did not complete",
+		resp:   nil,
+		err:    errors.New("i/o timeout"),
+		expect: ";; TCP MSS 536: response did not complete: i/o timeout\n",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs, out strings.Builder
+			task := newTestTask()
+			task.TCPMSS = 536
+			task.ResponseWriter = &out
+			task.reportTCPMSS(context.Background(), newTestLogger(&logs), tc.resp, tc.err)
+			if !strings.HasPrefix(out.String(), tc.expect) {
+				t.Fatalf("expected %q at the beginning of %q", tc.expect, out.String())
+			}
+			var ev struct {
+				Msg       string `json:"msg"`
+				Completed bool   `json:"completed"`
+				TCPMSS    int    `json:"tcpMss"`
+			}
+			if err := json.Unmarshal([]byte(logs.String()), &ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Msg != "dnsTcpMss" || ev.Completed != (tc.err == nil) || ev.TCPMSS != 536 {
+				t.Fatalf("unexpected event: %+v", ev)
+			}
+		})
+	}
+
+	t.Run("without the TCP MSS", func(t *testing.T) {
+		var logs, out strings.Builder
+		task := newTestTask()
+		task.ResponseWriter = &out
+		task.reportTCPMSS(context.Background(), newTestLogger(&logs), resp, nil)
+		if out.Len() > 0 || logs.Len() > 0 {
+			t.Fatalf("unexpected output: %q %q", out.String(), logs.String())
+		}
+	})
+}
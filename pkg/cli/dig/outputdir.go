@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// outputRecord is the JSON record we write for each query
+// into the directory selected using the OutputDir field.
+type outputRecord struct {
+	// Name is the name we're resolving.
+	Name string `json:"name"`
+
+	// QueryType is the query type (e.g., "A").
+	QueryType string `json:"queryType"`
+
+	// ServerAddr is the address of the server we queried.
+	ServerAddr string `json:"serverAddr"`
+
+	// ServerProtocol is the protocol we used to query the server.
+	ServerProtocol string `json:"serverProtocol"`
+
+	// Rcode is the response code, if we received a response.
+	Rcode string `json:"rcode,omitempty"`
+
+	// Answers contains the answers, if we received a response.
+	Answers []string `json:"answers,omitempty"`
+
+	// Err is the error that occurred, if any.
+	Err string `json:"err,omitempty"`
+}
+
+// outputPath returns the path of the file where to write the result of
+// querying the given server, which is OutputDir/<name>/<type>/<server>.json.
+func (task *Task) outputPath(server string) string {
+	return filepath.Join(
+		task.OutputDir,
+		escapePathSegment(task.Name),
+		escapePathSegment(task.QueryType),
+		escapePathSegment(server)+".json",
+	)
+}
+
+// newOutputRecord returns the [*outputRecord] describing the result
+// of querying the server with the given address, where either the
+// response or the error may be nil.
+func (task *Task) newOutputRecord(addr *dnscore.ServerAddr, resp *dns.Msg, err error) *outputRecord {
+	record := &outputRecord{
+		Name:           task.Name,
+		QueryType:      task.QueryType,
+		ServerAddr:     addr.Address,
+		ServerProtocol: string(addr.Protocol),
+	}
+	if resp != nil {
+		record.Rcode = dns.RcodeToString[resp.Rcode]
+		for _, ans := range resp.Answer {
+			record.Answers = append(record.Answers, ans.String())
+		}
+	}
+	if err != nil {
+		record.Err = err.Error()
+	}
+	return record
+}
+
+// writeOutput writes the result of querying the given server into
+// the OutputDir, creating the required directories as needed.
+func (task *Task) writeOutput(server string, addr *dnscore.ServerAddr, resp *dns.Msg, err error) error {
+	data, err := json.MarshalIndent(task.newOutputRecord(addr, resp, err), "", "  ")
+	if err != nil {
+		return err
+	}
+	path := task.outputPath(server)
+	if err := task.FS.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	filep, err := task.FS.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := filep.Write(append(data, '\n')); err != nil {
+		filep.Close()
+		return err
+	}
+	return filep.Close()
+}
+
+// escapePathSegment escapes the given string such that it is safe to
+// use it as a single path segment on all the supported systems.
+//
+// We keep ASCII letters, digits, '-', '_', and '.' and we replace any
+// other byte with its percent-encoded representation (e.g., ':' becomes
+// "%3A"). We also escape the dots of the "." and ".." segments, which
+// would otherwise refer to the current and parent directories.
+func escapePathSegment(value string) string {
+	if value == "." || value == ".." {
+		return strings.Repeat("%2E", len(value))
+	}
+	var builder strings.Builder
+	for idx := 0; idx < len(value); idx++ {
+		ch := value[idx]
+		switch {
+		case 'a' <= ch && ch <= 'z', 'A' <= ch && ch <= 'Z', '0' <= ch && ch <= '9':
+			builder.WriteByte(ch)
+		case ch == '-' || ch == '_' || ch == '.':
+			builder.WriteByte(ch)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", ch)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/common/fsx"
+	"github.com/rbmk-project/dnscore"
+)
+
+func TestEscapePathSegment(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"www.example.com", "www.example.com"},
+		{"8.8.8.8", "8.8.8.8"},
+		{"::1", "%3A%3A1"},
+		{"a/b\\c", "a%2Fb%5Cc"},
+		{".", "%2E"},
+		{"..", "%2E%2E"},
+		{"ex ample_-", "ex%20ample_-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := escapePathSegment(tt.input); got != tt.expect {
+				t.Fatalf("expected %q, got %q", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestTaskWriteOutput(t *testing.T) {
+	txp := &mockTransport{
+		responses: map[string][]dns.RR{
+			"8.8.8.8:53": {newTestA("93.184.216.34")},
+		},
+	}
+
+	readRecord := func(t *testing.T, path string) *outputRecord {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var record outputRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			t.Fatal(err)
+		}
+		return &record
+	}
+
+	t.Run("we write one file per server", func(t *testing.T) {
+		task := newTestTask()
+		task.FS = fsx.OsFS{}
+		task.OutputDir = t.TempDir()
+		task.Servers = []string{"::1", "8.8.8.8"}
+		_, err := task.exchangeInOrder(context.Background(), txp, dnscore.ProtocolUDP, newTestQuery(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		record := readRecord(t, filepath.Join(task.OutputDir, "www.example.com", "A", "8.8.8.8.json"))
+		if record.ServerAddr != "8.8.8.8:53" || record.ServerProtocol != "udp" || record.Rcode != "NOERROR" {
+			t.Fatalf("unexpected record: %+v", record)
+		}
+		if len(record.Answers) != 1 || record.Answers[0] != newTestA("93.184.216.34").String() {
+			t.Fatalf("unexpected answers: %+v", record.Answers)
+		}
+
+		record = readRecord(t, filepath.Join(task.OutputDir, "www.example.com", "A", "%3A%3A1.json"))
+		if record.Err != "mocked error" || record.Rcode != "" || len(record.Answers) != 0 {
+			t.Fatalf("unexpected record: %+v", record)
+		}
+	})
+
+	t.Run("we fail if we cannot write the output", func(t *testing.T) {
+		task := newTestTask()
+		task.FS = fsx.OsFS{}
+		task.OutputDir = filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(task.OutputDir, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := task.exchange(context.Background(), txp, dnscore.ProtocolUDP, "8.8.8.8", newTestQuery(t))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// The output formats selecting how we write responses (see OutputFormat).
+const (
+	// outputFormatDig writes the human readable response to the ResponseWriter.
+	outputFormatDig = "dig"
+
+	// outputFormatAnswers writes the answer RRs in presentation format.
+	outputFormatAnswers = "answers"
+
+	// outputFormatCSV writes the answer RRs as CSV rows (see csvRows).
+	outputFormatCSV = "csv"
+
+	// outputFormatJSON writes each response as a JSON [outputRecord].
+	outputFormatJSON = "json"
+
+	// outputFormatShort writes the value of each answer (see formatShort).
+	outputFormatShort = "short"
+
+	// outputFormatShortIP is like outputFormatShort but only writes addresses.
+	outputFormatShortIP = "short=ip"
+)
+
+// outputFormatNames contains the formats that --output-format accepts.
+var outputFormatNames = map[string]bool{
+	outputFormatDig:     true,
+	outputFormatAnswers: true,
+	outputFormatCSV:     true,
+	outputFormatJSON:    true,
+	outputFormatShort:   true,
+}
+
+// outputFormatNone is the pseudo format selected by +noall, which
+// suppresses the human readable response unless a later argument
+// selects another format (e.g., `+noall +answer`).
+const outputFormatNone = "none"
+
+// outputFormatSource is a command line argument selecting the output
+// format, either directly (e.g., --output-format) or as an alias of
+// one of the output formats (e.g., --csv or +short).
+type outputFormatSource struct {
+	// Arg is the argument as the user wrote it (e.g., "+short").
+	Arg string
+
+	// Format is the selected format or outputFormatNone.
+	Format string
+}
+
+// mergeOutputFormats merges two formats selected on the command line and
+// returns the resulting format, or false when they contradict each other.
+func mergeOutputFormats(current, next string) (string, bool) {
+	switch {
+	case current == next:
+		return current, true
+	case current == outputFormatNone:
+		return next, next != outputFormatDig
+	case next == outputFormatNone:
+		return current, current != outputFormatDig
+	case current == outputFormatShort && next == outputFormatShortIP,
+		current == outputFormatShortIP && next == outputFormatShort:
+		return outputFormatShortIP, true // +short=ip refines short
+	default:
+		return "", false
+	}
+}
+
+// resolveOutputFormat returns the output format selected by the given
+// sources in argv order, defaulting to outputFormatDig, and whether we
+// should suppress the output entirely (i.e., when only +noall is used).
+//
+// We fail when two sources contradict each other (e.g., --csv and +short,
+// or +noall and --output-format dig), rather than letting either silently
+// override the other.
+func resolveOutputFormat(sources []outputFormatSource) (string, bool, error) {
+	if len(sources) <= 0 {
+		return outputFormatDig, false, nil
+	}
+	format := sources[0].Format
+	for idx := 1; idx < len(sources); idx++ {
+		merged, ok := mergeOutputFormats(format, sources[idx].Format)
+		if !ok {
+			return "", false, fmt.Errorf("%s conflicts with %s", sources[idx].Arg, sources[idx-1].Arg)
+		}
+		format = merged
+	}
+	if format == outputFormatNone {
+		return outputFormatDig, true, nil
+	}
+	return format, false, nil
+}
+
+// setOutputFormat sets the OutputFormat and configures the writers for
+// it, writing the selected output to the given stdout [io.Writer]: the
+// ResponseWriter for the dig format and the ShortWriter otherwise.
+func (task *Task) setOutputFormat(format string, stdout io.Writer) error {
+	switch format {
+	case outputFormatDig:
+		task.OutputFormat = format
+		task.ResponseWriter = stdout
+		task.ShortWriter = io.Discard
+		return nil
+
+	case outputFormatAnswers, outputFormatCSV, outputFormatJSON, outputFormatShort, outputFormatShortIP:
+		task.OutputFormat = format
+		task.ResponseWriter = io.Discard
+		task.ShortWriter = stdout
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// formatResponse returns the given response received from the given
+// server formatted according to the OutputFormat, or an empty string
+// for the dig format, which streamResponse writes separately.
+func (task *Task) formatResponse(addr *dnscore.ServerAddr, resp *dns.Msg) string {
+	switch task.OutputFormat {
+	case outputFormatAnswers:
+		return formatAnswers(resp)
+	case outputFormatCSV:
+		return formatCSV(csvRows(resp)...)
+	case outputFormatJSON:
+		return task.formatJSON(addr, resp)
+	case outputFormatShort, outputFormatShortIP:
+		return task.formatShort(resp)
+	default:
+		return ""
+	}
+}
+
+// formatJSON returns the given response received from the given server
+// as a single-line JSON [outputRecord], like the ones we write into
+// the OutputDir, such that the output is newline-delimited JSON.
+func (task *Task) formatJSON(addr *dnscore.ServerAddr, resp *dns.Msg) string {
+	data, err := json.Marshal(task.newOutputRecord(addr, resp, nil))
+	if err != nil {
+		return "" // cannot happen: the record only contains strings
+	}
+	return string(data) + "\n"
+}
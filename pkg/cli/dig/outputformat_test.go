@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/common/cliutils"
+	"github.com/rbmk-project/dnscore"
+	"github.com/rbmk-project/rbmk/internal/testable"
+)
+
+func TestTaskSetOutputFormat(t *testing.T) {
+	stdout := &strings.Builder{}
+	cases := []struct {
+		format         string
+		expectResponse io.Writer
+		expectShort    io.Writer
+		expectFailure  bool
+	}{
+		{format: "dig", expectResponse: stdout, expectShort: io.Discard},
+		{format: "answers", expectResponse: io.Discard, expectShort: stdout},
+		{format: "csv", expectResponse: io.Discard, expectShort: stdout},
+		{format: "json", expectResponse: io.Discard, expectShort: stdout},
+		{format: "short", expectResponse: io.Discard, expectShort: stdout},
+		{format: "short=ip", expectResponse: io.Discard, expectShort: stdout},
+		{format: "none", expectFailure: true},
+		{format: "", expectFailure: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.format, func(t *testing.T) {
+			task := newTestTask()
+			err := task.setOutputFormat(tc.format, stdout)
+			if tc.expectFailure {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if task.OutputFormat != tc.format {
+				t.Fatalf("expected %s, got %s", tc.format, task.OutputFormat)
+			}
+			if task.ResponseWriter != tc.expectResponse || task.ShortWriter != tc.expectShort {
+				t.Fatal("unexpected writers")
+			}
+		})
+	}
+}
+
+func TestResolveOutputFormat(t *testing.T) {
+	var (
+		answerOnly = outputFormatSource{Arg: "--answers-only", Format: outputFormatAnswers}
+		answer     = outputFormatSource{Arg: "+answer", Format: outputFormatAnswers}
+		csv        = outputFormatSource{Arg: "--csv", Format: outputFormatCSV}
+		dig        = outputFormatSource{Arg: "--output-format=dig", Format: outputFormatDig}
+		json       = outputFormatSource{Arg: "--output-format=json", Format: outputFormatJSON}
+		noall      = outputFormatSource{Arg: "+noall", Format: outputFormatNone}
+		short      = outputFormatSource{Arg: "--output-format=short", Format: outputFormatShort}
+		shortIP    = outputFormatSource{Arg: "+short=ip", Format: outputFormatShortIP}
+	)
+	cases := []struct {
+		name          string
+		sources       []outputFormatSource
+		expectFormat  string
+		expectDiscard bool
+		expectErr     string
+	}{
+		{name: "default", expectFormat: "dig"},
+		{name: "+noall alone", sources: []outputFormatSource{noall}, expectFormat: "dig", expectDiscard: true},
+		{name: "+noall +answer", sources: []outputFormatSource{noall, answer}, expectFormat: "answers"},
+		{name: "+noall --answers-only", sources: []outputFormatSource{noall, answerOnly}, expectFormat: "answers"},
+		{name: "--output-format=json +noall", sources: []outputFormatSource{json, noall}, expectFormat: "json"},
+		{name: "+short=ip --output-format=short", sources: []outputFormatSource{shortIP, short}, expectFormat: "short=ip"},
+		{name: "--output-format=short +short=ip", sources: []outputFormatSource{short, shortIP}, expectFormat: "short=ip"},
+		{name: "--csv --csv", sources: []outputFormatSource{csv, csv}, expectFormat: "csv"},
+		{
+			name:      "+noall --output-format=dig",
+			sources:   []outputFormatSource{noall, dig},
+			expectErr: "--output-format=dig conflicts with +noall",
+		},
+		{
+			name:      "--output-format=dig +noall",
+			sources:   []outputFormatSource{dig, noall},
+			expectErr: "+noall conflicts with --output-format=dig",
+		},
+		{
+			name:      "--csv +short=ip",
+			sources:   []outputFormatSource{csv, shortIP},
+			expectErr: "+short=ip conflicts with --csv",
+		},
+		{
+			name:      "--answers-only --output-format=json",
+			sources:   []outputFormatSource{answerOnly, json},
+			expectErr: "--output-format=json conflicts with --answers-only",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			format, discard, err := resolveOutputFormat(tc.sources)
+			if tc.expectErr != "" {
+				if err == nil || err.Error() != tc.expectErr {
+					t.Fatalf("expected %q, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tc.expectFormat || discard != tc.expectDiscard {
+				t.Fatalf("expected %s/%v, got %s/%v", tc.expectFormat, tc.expectDiscard, format, discard)
+			}
+		})
+	}
+}
+
+func TestTaskFormatJSON(t *testing.T) {
+	task := newTestTask()
+	task.OutputFormat = outputFormatJSON
+	resp := &dns.Msg{}
+	resp.SetReply(newTestQuery(t))
+	resp.Answer = append(resp.Answer, newTestA("93.184.215.14"))
+
+	out := task.formatResponse(newTestServerAddr(), resp)
+	if !strings.HasSuffix(out, "\n") || strings.Count(out, "\n") != 1 {
+		t.Fatalf("expected a single line, got %q", out)
+	}
+	var record outputRecord
+	if err := json.Unmarshal([]byte(out), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.ServerAddr != "8.8.8.8:53" || record.ServerProtocol != string(dnscore.ProtocolUDP) ||
+		record.Rcode != "NOERROR" || len(record.Answers) != 1 {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+}
+
+func TestCommandOutputFormat(t *testing.T) {
+	// we fail dialing rather than actually connecting, such that the
+	// accepted formats fail with the dial error rather than a usage error
+	testable.DialContext.Set(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return nil, errors.New("mocked dial error")
+	})
+	defer testable.DialContext.Set(nil)
+
+	cmd := NewCommand()
+	const dialErr = "query round-trip failed: 127.0.0.1: mocked dial error"
+	cases := []struct {
+		argv      []string
+		expectErr string
+	}{
+		{argv: []string{"--output-format=dig"}, expectErr: dialErr},
+		{argv: []string{"--output-format=answers"}, expectErr: dialErr},
+		{argv: []string{"--output-format=csv"}, expectErr: dialErr},
+		{argv: []string{"--output-format=json"}, expectErr: dialErr},
+		{argv: []string{"--output-format=short"}, expectErr: dialErr},
+		{argv: []string{"+noall", "--output-format=dig"}, expectErr: "--output-format=dig conflicts with +noall"},
+		{argv: []string{"--csv", "+short"}, expectErr: "+short conflicts with --csv"},
+		{argv: []string{"--output-format", "yaml"}, expectErr: "unsupported output format: yaml"},
+	}
+	for _, tc := range cases {
+		t.Run(strings.Join(tc.argv, " "), func(t *testing.T) {
+			argv := append([]string{"dig", "@127.0.0.1"}, tc.argv...)
+			argv = append(argv, "www.example.com")
+			err := cmd.Main(context.Background(), cliutils.StandardEnvironment{}, argv...)
+			if err == nil || err.Error() != tc.expectErr {
+				t.Fatalf("expected %q, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// parallelResult is the result of querying one of the ParallelServers.
+type parallelResult struct {
+	// resp is the response or nil.
+	resp *dns.Msg
+
+	// err is the error or nil.
+	err error
+
+	// response and short buffer the output that the goroutine
+	// would otherwise write to the task writers, such that we
+	// can write it in order once all queries are done.
+	response, short bytes.Buffer
+}
+
+// queryParallel sends the same query to each server in ParallelServers
+// concurrently and merges the A and AAAA answers of the valid responses,
+// writing which servers contributed each address to the ResponseWriter.
+//
+// We emit a dnsParallelServer structured log event for each server and a
+// dnsParallelSummary event describing the merged answers.
+//
+// We return the errors that occurred when querying the servers, if any,
+// after having written the merged answers for the servers that responded.
+func (task *Task) queryParallel(
+	ctx context.Context,
+	logger *slog.Logger,
+	txp dnsTransport,
+	protocol dnscore.Protocol,
+	query *dns.Msg,
+) error {
+	// Query each server in its own goroutine, buffering the output
+	t0 := time.Now()
+	results := make([]*parallelResult, len(task.ParallelServers))
+	wg := &sync.WaitGroup{}
+	for idx, address := range task.ParallelServers {
+		result := &parallelResult{}
+		results[idx] = result
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempt := *task
+			attempt.ResponseWriter = &result.response
+			attempt.ShortWriter = &result.short
+			result.resp, result.err = attempt.exchange(ctx, txp, protocol, address, query)
+			if result.err == nil {
+				result.err = dnscore.ValidateResponse(query, result.resp)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Write the output and merge the answers in the order of the servers
+	var (
+		errv         []error
+		contributors = make(map[netip.Addr][]string)
+	)
+	for idx, address := range task.ParallelServers {
+		result := results[idx]
+		fmt.Fprintf(task.ResponseWriter, "\n;; Server: @%s\n", address)
+		task.ResponseWriter.Write(result.response.Bytes())
+		task.ShortWriter.Write(result.short.Bytes())
+
+		var addrs []netip.Addr
+		if result.err == nil {
+			addrs = answerAddrs(result.resp)
+		}
+		for _, addr := range addrs {
+			if !slices.Contains(contributors[addr], address) {
+				contributors[addr] = append(contributors[addr], address)
+			}
+		}
+		logger.InfoContext(
+			ctx,
+			"dnsParallelServer",
+			slog.Any("dnsAddrs", addrs),
+			slog.Any("err", result.err),
+			slog.String("serverAddr", address),
+			slog.Time("t", time.Now()),
+		)
+		if result.err != nil {
+			errv = append(errv, fmt.Errorf("%s: %w", address, result.err))
+		}
+	}
+
+	// Write and log the merged answers
+	merged := slices.SortedFunc(maps.Keys(contributors), netip.Addr.Compare)
+	fmt.Fprintf(task.ResponseWriter, "\n;; Merged answers:\n")
+	for _, addr := range merged {
+		servers := contributors[addr]
+		fmt.Fprintf(task.ResponseWriter, "%s\t@%s\n", addr, strings.Join(servers, " @"))
+	}
+	logger.InfoContext(
+		ctx,
+		"dnsParallelSummary",
+		slog.Any("dnsAddrs", merged),
+		slog.Any("dnsContributors", contributors),
+		slog.Int("failures", len(errv)),
+		slog.Time("t0", t0),
+		slog.Time("t", time.Now()),
+	)
+	return errors.Join(errv...)
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+func TestTaskQueryParallel(t *testing.T) {
+	txp := &mockTransport{
+		responses: map[string][]dns.RR{
+			"8.8.8.8:53": {newTestA("93.184.216.34"), newTestA("93.184.216.35")},
+			"1.1.1.1:53": {newTestA("93.184.216.35"), newTestA("93.184.216.36")},
+			"9.9.9.9:53": {newTestA("93.184.216.37")},
+		},
+	}
+
+	// run runs queryParallel with the given servers and returns
+	// the output, the dnsParallelSummary event, and the error.
+	type summary struct {
+		Msg          string              `json:"msg"`
+		Addrs        []string            `json:"dnsAddrs"`
+		Contributors map[string][]string `json:"dnsContributors"`
+		Failures     int                 `json:"failures"`
+	}
+	run := func(t *testing.T, servers ...string) (string, *summary, error) {
+		var out, logs strings.Builder
+		task := newTestTask()
+		task.ParallelServers = servers
+		task.ResponseWriter = &out
+		err := task.queryParallel(context.Background(),
+			newTestLogger(&logs), txp, dnscore.ProtocolUDP, newTestQuery(t))
+		lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+		if len(lines) != len(servers)+1 {
+			t.Fatalf("unexpected number of events: %d", len(lines))
+		}
+		var ev summary
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Msg != "dnsParallelSummary" {
+			t.Fatalf("unexpected event: %s", ev.Msg)
+		}
+		return out.String(), &ev, err
+	}
+
+	t.Run("with overlapping answers", func(t *testing.T) {
+		out, ev, err := run(t, "8.8.8.8", "1.1.1.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := strings.Join([]string{
+			";; Merged answers:",
+			"93.184.216.34\t@8.8.8.8",
+			"93.184.216.35\t@8.8.8.8 @1.1.1.1",
+			"93.184.216.36\t@1.1.1.1",
+			"",
+		}, "\n")
+		if !strings.HasSuffix(out, expect) {
+			t.Fatalf("expected %q at the end of %q", expect, out)
+		}
+		if strings.Index(out, ";; Server: @8.8.8.8") > strings.Index(out, ";; Server: @1.1.1.1") {
+			t.Fatalf("expected the responses in the order of the servers, got %q", out)
+		}
+		if got := strings.Join(ev.Contributors["93.184.216.35"], ","); got != "8.8.8.8,1.1.1.1" {
+			t.Fatalf("unexpected contributors: %s", got)
+		}
+	})
+
+	t.Run("with disjoint answers and a failure", func(t *testing.T) {
+		out, ev, err := run(t, "8.8.8.8", "9.9.9.9", "10.0.0.1")
+		if err == nil || !strings.Contains(err.Error(), "10.0.0.1") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(out, "93.184.216.37\t@9.9.9.9\n") {
+			t.Fatalf("unexpected output: %q", out)
+		}
+		if len(ev.Addrs) != 3 || ev.Failures != 1 {
+			t.Fatalf("unexpected summary: %+v", ev)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// phaseNames maps the structured log events marking the end of
+// a phase to the name we use when writing the phase timings.
+var phaseNames = map[string]string{
+	"connectDone":      "connect",
+	"tlsHandshakeDone": "tlsHandshake",
+	"dnsResponse":      "query",
+}
+
+// phase is a phase of the query (e.g., connect) and its timing.
+type phase struct {
+	// Name is the phase name (e.g., "connect").
+	Name string
+
+	// RemoteAddr is the remote address of the connection.
+	RemoteAddr string
+
+	// T0 is when the phase started.
+	T0 time.Time
+
+	// T is when the phase ended.
+	T time.Time
+}
+
+// phaseTracer collects the phases of the query using the structured
+// log events emitted while running the task, which include the time
+// when each phase started and ended.
+//
+// The zero value is ready to use.
+type phaseTracer struct {
+	mu     sync.Mutex
+	phases []phase
+}
+
+// wrap returns a [slog.Handler] that records the phases
+// and forwards each record to the given [slog.Handler].
+func (pt *phaseTracer) wrap(handler slog.Handler) slog.Handler {
+	return &phaseHandler{handler: handler, tracer: pt}
+}
+
+// add records the phase described by the given record, if any.
+func (pt *phaseTracer) add(record slog.Record) {
+	name, ok := phaseNames[record.Message]
+	if !ok {
+		return
+	}
+	ph := phase{Name: name}
+	record.Attrs(func(attr slog.Attr) bool {
+		switch attr.Key {
+		case "remoteAddr":
+			ph.RemoteAddr = attr.Value.String()
+		case "t0":
+			ph.T0 = attr.Value.Time()
+		case "t":
+			ph.T = attr.Value.Time()
+		}
+		return true
+	})
+	pt.mu.Lock()
+	pt.phases = append(pt.phases, ph)
+	pt.mu.Unlock()
+}
+
+// write writes the phase timings to the given [io.Writer], followed by
+// the total time elapsed between the start of the first phase and the
+// end of the last phase.
+func (pt *phaseTracer) write(w io.Writer) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	fmt.Fprintf(w, "\n;; Phases:\n")
+	var t0, t time.Time
+	for _, ph := range pt.phases {
+		fmt.Fprintf(w, ";; %-12s %-24s %s\n", ph.Name, ph.RemoteAddr, ph.T.Sub(ph.T0))
+		if t0.IsZero() || ph.T0.Before(t0) {
+			t0 = ph.T0
+		}
+		if ph.T.After(t) {
+			t = ph.T
+		}
+	}
+	fmt.Fprintf(w, ";; %-12s %-24s %s\n\n", "total", "", t.Sub(t0))
+}
+
+// phaseHandler is the [slog.Handler] returned by [*phaseTracer.wrap].
+type phaseHandler struct {
+	handler slog.Handler
+	tracer  *phaseTracer
+}
+
+var _ slog.Handler = &phaseHandler{}
+
+// Enabled implements [slog.Handler].
+func (ph *phaseHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return ph.handler.Enabled(ctx, level)
+}
+
+// Handle implements [slog.Handler].
+func (ph *phaseHandler) Handle(ctx context.Context, record slog.Record) error {
+	ph.tracer.add(record)
+	return ph.handler.Handle(ctx, record)
+}
+
+// WithAttrs implements [slog.Handler].
+func (ph *phaseHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &phaseHandler{handler: ph.handler.WithAttrs(attrs), tracer: ph.tracer}
+}
+
+// WithGroup implements [slog.Handler].
+func (ph *phaseHandler) WithGroup(name string) slog.Handler {
+	return &phaseHandler{handler: ph.handler.WithGroup(name), tracer: ph.tracer}
+}
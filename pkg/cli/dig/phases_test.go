@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhaseTracer(t *testing.T) {
+	// Emit the events of a DoT query, along with unrelated events,
+	// using a logger with attributes to exercise WithAttrs
+	var logs strings.Builder
+	tracer := &phaseTracer{}
+	logger := slog.New(tracer.wrap(newTestLogger(&logs).Handler())).With("campaignId", "x")
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	emit := func(msg string, start, end time.Duration) {
+		logger.InfoContext(
+			context.Background(),
+			msg,
+			slog.String("remoteAddr", "8.8.8.8:853"),
+			slog.Time("t0", t0.Add(start)),
+			slog.Time("t", t0.Add(end)),
+		)
+	}
+	emit("connectStart", 0, 0)
+	emit("connectDone", 0, 10*time.Millisecond)
+	emit("tlsHandshakeDone", 10*time.Millisecond, 35*time.Millisecond)
+	emit("dnsQuery", 35*time.Millisecond, 35*time.Millisecond)
+	emit("dnsResponse", 35*time.Millisecond, 55*time.Millisecond)
+
+	var out strings.Builder
+	tracer.write(&out)
+	for _, expect := range []string{
+		";; connect      8.8.8.8:853              10ms\n",
+		";; tlsHandshake 8.8.8.8:853              25ms\n",
+		";; query        8.8.8.8:853              20ms\n",
+		";; total                                 55ms\n",
+	} {
+		if !strings.Contains(out.String(), expect) {
+			t.Fatalf("expected %q in %q", expect, out.String())
+		}
+	}
+	if strings.Count(out.String(), "8.8.8.8:853") != 3 {
+		t.Fatalf("unexpected phases in %q", out.String())
+	}
+
+	// Make sure we still forward the records to the wrapped handler
+	if count := strings.Count(logs.String(), `"campaignId":"x"`); count != 5 {
+		t.Fatalf("expected 5 forwarded records, got %d", count)
+	}
+}
+
+func TestPhaseTracerWithoutPhases(t *testing.T) {
+	tracer := &phaseTracer{}
+	logger := slog.New(tracer.wrap(newTestLogger(io.Discard).Handler()))
+	logger.Info("dnsQuery")
+	var out strings.Builder
+	tracer.write(&out)
+	if got := out.String(); !strings.HasSuffix(got, ";; total                                 0s\n\n") {
+		t.Fatalf("unexpected output: %q", got)
+	}
+}
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+var (
+	// errBogonAnswer indicates that the response contains a bogon address.
+	errBogonAnswer = errors.New("response contains a bogon address")
+
+	// errEmptyAnswer indicates that the response contains no valid answers.
+	errEmptyAnswer = errors.New("response contains no valid answers")
+
+	// errPolicyViolation indicates that a valid response violates one of
+	// the policies explicitly requested on the command line. Like failed
+	// expectations, and unlike measurement failures, `--measure` does not
+	// suppress policy violations.
+	errPolicyViolation = errors.New("policy violation")
+)
+
+// checkPolicy checks whether a valid response for the given
+// query violates the FailOnBogon or FailOnEmpty policies.
+func (task *Task) checkPolicy(query, resp *dns.Msg) error {
+	// Check for NODATA, i.e., no answers matching the question
+	if task.FailOnEmpty && len(query.Question) > 0 {
+		if _, err := dnscore.ValidAnswers(query.Question[0], resp); errors.Is(err, dnscore.ErrNoData) {
+			return errEmptyAnswer
+		}
+	}
+
+	// Check for A and AAAA answers containing bogon addresses
+	if task.FailOnBogon {
+		if addr, found := findBogon(resp); found {
+			return fmt.Errorf("%w: %s", errBogonAnswer, addr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+func TestTaskCheckPolicy(t *testing.T) {
+	txp := &mockTransport{
+		responses: map[string][]dns.RR{
+			"8.8.8.8:53": {newTestA("93.184.216.34")},
+			"8.8.4.4:53": {newTestA("10.10.34.35")},
+			"1.1.1.1:53": {},
+		},
+	}
+
+	cases := []struct {
+		name        string
+		server      string
+		failOnBogon bool
+		failOnEmpty bool
+		expect      error
+	}{
+		{name: "no policy with bogon", server: "8.8.4.4", expect: nil},
+		{name: "no policy with empty", server: "1.1.1.1", expect: nil},
+		{name: "bogon policy with public address", server: "8.8.8.8", failOnBogon: true, expect: nil},
+		{name: "bogon policy with bogon address", server: "8.8.4.4", failOnBogon: true, expect: errBogonAnswer},
+		{name: "bogon policy with empty", server: "1.1.1.1", failOnBogon: true, expect: nil},
+		{name: "empty policy with answers", server: "8.8.8.8", failOnEmpty: true, expect: nil},
+		{name: "empty policy with empty", server: "1.1.1.1", failOnEmpty: true, expect: errEmptyAnswer},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := newTestTask()
+			task.FailOnBogon = tc.failOnBogon
+			task.FailOnEmpty = tc.failOnEmpty
+			query := newTestQuery(t)
+			resp, err := task.exchange(context.Background(), txp, dnscore.ProtocolUDP, tc.server, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := task.checkPolicy(query, resp); !errors.Is(err, tc.expect) {
+				t.Fatalf("expected %v, got %v", tc.expect, err)
+			}
+
+			// make sure --measure cannot suppress the violation
+			task.NoValidate = true // otherwise we fail with NODATA first
+			err = task.checkResponse(context.Background(), newTestLogger(io.Discard), query, resp)
+			if violated := errors.Is(err, errPolicyViolation); violated != (tc.expect != nil) {
+				t.Fatalf("expected a policy violation: %v, got %v", tc.expect != nil, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// errPollTimeout indicates that the condition selected using the
+// PollUntil field did not occur before the PollTimeout expired.
+var errPollTimeout = errors.New("condition not met before the poll timeout")
+
+// pollQueryTimeout bounds each query we send when polling, such that
+// a lost query does not consume the whole PollTimeout.
+const pollQueryTimeout = 5 * time.Second
+
+// pollConditionMet returns whether the given valid response for the
+// given query satisfies the condition selected using PollUntil.
+func (task *Task) pollConditionMet(query, resp *dns.Msg) bool {
+	switch task.PollUntil {
+	case "noerror":
+		if resp.Rcode != dns.RcodeSuccess || len(query.Question) <= 0 {
+			return false
+		}
+		_, err := dnscore.ValidAnswers(query.Question[0], resp)
+		return err == nil
+
+	case "nxdomain":
+		return resp.Rcode == dns.RcodeNameError
+
+	default:
+		return false
+	}
+}
+
+// pollUntil sends the query every PollInterval until the response
+// satisfies the condition selected using PollUntil, which is either
+// "noerror" (the name resolves to valid answers) or "nxdomain" (the
+// name does not exist), or until the PollTimeout expires.
+//
+// We return nil when the condition is met, an error wrapping
+// [errPollTimeout] when the PollTimeout expires, and the context
+// error when the given context is done. In all cases, we emit a
+// dnsPollSummary structured log event describing the outcome.
+func (task *Task) pollUntil(
+	ctx context.Context,
+	logger *slog.Logger,
+	txp dnsTransport,
+	protocol dnscore.Protocol,
+	query *dns.Msg,
+) error {
+	t0 := time.Now()
+	pollCtx, cancel := context.WithTimeout(ctx, task.PollTimeout)
+	defer cancel()
+
+	logSummary := func(polls int, met bool) {
+		logger.InfoContext(
+			ctx,
+			"dnsPollSummary",
+			slog.Bool("conditionMet", met),
+			slog.String("pollUntil", task.PollUntil),
+			slog.Int("polls", polls),
+			slog.Time("t0", t0),
+			slog.Time("t", time.Now()),
+		)
+	}
+
+	for polls := 1; ; polls++ {
+		queryCtx, queryCancel := context.WithTimeout(pollCtx, pollQueryTimeout)
+		resp, err := task.exchangeInOrder(queryCtx, txp, protocol, query)
+		queryCancel()
+		if err == nil {
+			err = dnscore.ValidateResponse(query, resp)
+		}
+		if err == nil && task.pollConditionMet(query, resp) {
+			logSummary(polls, true)
+			return nil
+		}
+
+		select {
+		case <-pollCtx.Done():
+			logSummary(polls, false)
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %s after %d polls", errPollTimeout, task.PollUntil, polls)
+		case <-time.After(task.PollInterval):
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+func TestTaskPollUntil(t *testing.T) {
+	// run runs pollUntil and returns the dnsPollSummary event and the error.
+	type summary struct {
+		Msg          string `json:"msg"`
+		ConditionMet bool   `json:"conditionMet"`
+		PollUntil    string `json:"pollUntil"`
+		Polls        int    `json:"polls"`
+	}
+	run := func(t *testing.T, ctx context.Context, txp dnsTransport, until string) (*summary, error) {
+		var logs strings.Builder
+		task := newTestTask()
+		task.PollInterval = 10 * time.Millisecond
+		task.PollTimeout = 100 * time.Millisecond
+		task.PollUntil = until
+		err := task.pollUntil(ctx, newTestLogger(&logs), txp, dnscore.ProtocolUDP, newTestQuery(t))
+		var ev summary
+		if err := json.Unmarshal([]byte(strings.TrimSpace(logs.String())), &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Msg != "dnsPollSummary" || ev.PollUntil != until {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+		return &ev, err
+	}
+
+	t.Run("until the name resolves", func(t *testing.T) {
+		// the name has no answers until the third query
+		txp := &changingTransport{
+			mockTransport: mockTransport{
+				responses: map[string][]dns.RR{"8.8.8.8:53": nil},
+			},
+			changeAt:   3,
+			changedRRs: []dns.RR{newTestA("93.184.216.34")},
+		}
+		ev, err := run(t, context.Background(), txp, "noerror")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ev.ConditionMet || ev.Polls != 3 {
+			t.Fatalf("unexpected summary: %+v", ev)
+		}
+	})
+
+	t.Run("until the name does not exist", func(t *testing.T) {
+		txp := &mockTransport{
+			responses: map[string][]dns.RR{"8.8.8.8:53": nil},
+			rcodes:    map[string]int{"8.8.8.8:53": dns.RcodeNameError},
+		}
+		ev, err := run(t, context.Background(), txp, "nxdomain")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ev.ConditionMet || ev.Polls != 1 {
+			t.Fatalf("unexpected summary: %+v", ev)
+		}
+	})
+
+	t.Run("when the poll timeout expires", func(t *testing.T) {
+		txp := &mockTransport{
+			responses: map[string][]dns.RR{"8.8.8.8:53": {newTestA("93.184.216.34")}},
+		}
+		ev, err := run(t, context.Background(), txp, "nxdomain")
+		if !errors.Is(err, errPollTimeout) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ev.ConditionMet || ev.Polls < 2 {
+			t.Fatalf("unexpected summary: %+v", ev)
+		}
+	})
+
+	t.Run("when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		ev, err := run(t, ctx, &mockTransport{}, "noerror")
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ev.ConditionMet || ev.Polls != 1 {
+			t.Fatalf("unexpected summary: %+v", ev)
+		}
+	})
+}
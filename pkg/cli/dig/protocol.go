@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rbmk-project/dnscore"
+)
+
+// protocolAliases maps protocol names and their aliases to DNS protocols.
+var protocolAliases = map[string]dnscore.Protocol{
+	"udp":   dnscore.ProtocolUDP,
+	"tcp":   dnscore.ProtocolTCP,
+	"dot":   dnscore.ProtocolDoT,
+	"tls":   dnscore.ProtocolDoT,
+	"doh":   dnscore.ProtocolDoH,
+	"https": dnscore.ProtocolDoH,
+}
+
+// parseProtocol parses a case-insensitive protocol name or alias
+// (e.g., "udp", "tls", "https") and returns the DNS protocol.
+//
+// We do not recognise "doq" and "quic" because the version of
+// dnscore we depend on does not implement DNS-over-QUIC.
+func parseProtocol(value string) (dnscore.Protocol, error) {
+	protocol, ok := protocolAliases[strings.ToLower(value)]
+	if !ok {
+		return "", fmt.Errorf("unsupported protocol: %s", value)
+	}
+	return protocol, nil
+}
+
+// setProtocol parses the given protocol name or alias using parseProtocol
+// and sets the Protocol and the default ServerPort for the protocol. Like
+// the "+" options selecting the protocol, we also stop waiting for duplicate
+// responses, which +udp=wait-duplicates may have previously enabled.
+func (task *Task) setProtocol(value string) error {
+	protocol, err := parseProtocol(value)
+	if err != nil {
+		return err
+	}
+	task.Protocol = string(protocol)
+	task.ServerPort = defaultServerPorts[protocol]
+	task.WaitDuplicates = false
+	return nil
+}
@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"testing"
+
+	"github.com/rbmk-project/dnscore"
+)
+
+func TestParseProtocol(t *testing.T) {
+	cases := []struct {
+		value   string
+		expect  dnscore.Protocol
+		failure bool
+	}{
+		{value: "udp", expect: dnscore.ProtocolUDP},
+		{value: "UDP", expect: dnscore.ProtocolUDP},
+		{value: "tcp", expect: dnscore.ProtocolTCP},
+		{value: "dot", expect: dnscore.ProtocolDoT},
+		{value: "tls", expect: dnscore.ProtocolDoT},
+		{value: "doh", expect: dnscore.ProtocolDoH},
+		{value: "Https", expect: dnscore.ProtocolDoH},
+		{value: "doq", failure: true},
+		{value: "quic", failure: true},
+		{value: "", failure: true},
+		{value: "smtp", failure: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := parseProtocol(tc.value)
+			if tc.failure {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expect {
+				t.Fatalf("expected %s, got %s", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestTaskSetProtocol(t *testing.T) {
+	cases := []struct {
+		value        string
+		expectProto  string
+		expectPort   string
+		expectFailed bool
+	}{
+		{value: "udp", expectProto: "udp", expectPort: "53"},
+		{value: "tcp", expectProto: "tcp", expectPort: "53"},
+		{value: "dot", expectProto: "dot", expectPort: "853"},
+		{value: "tls", expectProto: "dot", expectPort: "853"},
+		{value: "doh", expectProto: "doh", expectPort: "443"},
+		{value: "https", expectProto: "doh", expectPort: "443"},
+		{value: "doq", expectFailed: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			task := newTestTask()
+			err := task.setProtocol(tc.value)
+			if tc.expectFailed {
+				if err == nil || task.Protocol != "udp" || task.ServerPort != "53" {
+					t.Fatalf("expected failure without changes, got %v %s %s", err, task.Protocol, task.ServerPort)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if task.Protocol != tc.expectProto || task.ServerPort != tc.expectPort {
+				t.Fatalf("expected %s:%s, got %s:%s", tc.expectProto, tc.expectPort, task.Protocol, task.ServerPort)
+			}
+		})
+	}
+}
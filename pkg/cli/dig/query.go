@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// queryOptionRecursionDesired returns a [dnscore.QueryOption]
+// that sets or clears the RD (recursion desired) bit.
+func queryOptionRecursionDesired(value bool) dnscore.QueryOption {
+	return func(query *dns.Msg) error {
+		query.RecursionDesired = value
+		return nil
+	}
+}
+
+// queryOptionCheckingDisabled returns a [dnscore.QueryOption]
+// that sets or clears the CD (checking disabled) bit.
+func queryOptionCheckingDisabled(value bool) dnscore.QueryOption {
+	return func(query *dns.Msg) error {
+		query.CheckingDisabled = value
+		return nil
+	}
+}
+
+// ednsFlagNames maps the names of the EDNS0 header flags to their bits
+// inside the 16-bit flags field of the OPT record (see RFC 6891).
+var ednsFlagNames = map[string]uint16{
+	"do": 1 << 15, // DNSSEC OK (RFC 3225)
+	"co": 1 << 14, // Compact Answers OK (RFC 9824)
+}
+
+// parseEDNSFlags parses a comma-separated list of EDNS0 header flag
+// names (e.g., "do,co") or hexadecimal values (e.g., "0x0001").
+func parseEDNSFlags(value string) (uint16, error) {
+	var flags uint16
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if bits, ok := ednsFlagNames[entry]; ok {
+			flags |= bits
+			continue
+		}
+		if hexval, ok := strings.CutPrefix(entry, "0x"); ok {
+			bits, err := strconv.ParseUint(hexval, 16, 16)
+			if err == nil {
+				flags |= uint16(bits)
+				continue
+			}
+		}
+		return 0, fmt.Errorf("unknown EDNS0 flag: %q", entry)
+	}
+	return flags, nil
+}
+
+// queryOptionEDNS0Flags returns a [dnscore.QueryOption] that sets the
+// given bits in the EDNS0 header flags of the OPT record, which must
+// have already been added using [dnscore.QueryOptionEDNS0].
+func queryOptionEDNS0Flags(flags uint16) dnscore.QueryOption {
+	return func(query *dns.Msg) error {
+		opt := query.IsEdns0()
+		if opt == nil {
+			return errors.New("cannot set EDNS0 flags without an OPT record")
+		}
+		opt.Hdr.Ttl |= uint32(flags)
+		return nil
+	}
+}
+
+// queryOptionEDNS0TCPKeepalive returns a [dnscore.QueryOption] that adds the
+// edns-tcp-keepalive option (RFC 7828) to the OPT record, which must have
+// already been added using [dnscore.QueryOptionEDNS0]. The timeout is in
+// units of 100 milliseconds. Note that RFC 7828 requires clients to send
+// a zero timeout, which we omit, but we allow sending other values to test
+// how servers react to them.
+func queryOptionEDNS0TCPKeepalive(timeout uint16) dnscore.QueryOption {
+	return func(query *dns.Msg) error {
+		opt := query.IsEdns0()
+		if opt == nil {
+			return errors.New("cannot set EDNS0 TCP keepalive without an OPT record")
+		}
+		opt.Option = append(opt.Option, &dns.EDNS0_TCP_KEEPALIVE{
+			Code:    dns.EDNS0TCPKEEPALIVE,
+			Timeout: timeout,
+		})
+		return nil
+	}
+}
+
+// responseTCPKeepalive returns the idle timeout advertised by the server
+// using the edns-tcp-keepalive option (RFC 7828) of the response, and
+// whether the response contains such an option with a timeout.
+func responseTCPKeepalive(resp *dns.Msg) (time.Duration, bool) {
+	opt := resp.IsEdns0()
+	if opt == nil {
+		return 0, false
+	}
+	for _, option := range opt.Option {
+		if keepalive, ok := option.(*dns.EDNS0_TCP_KEEPALIVE); ok && keepalive.Timeout > 0 {
+			return time.Duration(keepalive.Timeout) * 100 * time.Millisecond, true
+		}
+	}
+	return 0, false
+}
+
+// parseClientSubnet parses the value of the +subnet=ADDR[/PREFIX] option,
+// where a missing PREFIX means using all the bits of the address.
+func parseClientSubnet(value string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid client subnet: %s", value)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// queryOptionEDNS0ClientSubnet returns a [dnscore.QueryOption] that adds
+// the edns-client-subnet option (RFC 7871) for the given prefix to the OPT
+// record, which must have already been added using [dnscore.QueryOptionEDNS0].
+func queryOptionEDNS0ClientSubnet(prefix netip.Prefix) dnscore.QueryOption {
+	return func(query *dns.Msg) error {
+		opt := query.IsEdns0()
+		if opt == nil {
+			return errors.New("cannot set EDNS0 client subnet without an OPT record")
+		}
+		subnet := &dns.EDNS0_SUBNET{
+			Code:          dns.EDNS0SUBNET,
+			Family:        1,
+			SourceNetmask: uint8(prefix.Bits()),
+			Address:       prefix.Addr().AsSlice(),
+		}
+		if prefix.Addr().Is6() {
+			subnet.Family = 2
+		}
+		opt.Option = append(opt.Option, subnet)
+		return nil
+	}
+}
+
+// responseClientSubnet returns the edns-client-subnet option (RFC 7871)
+// of the response, whose SourceScope indicates for which prefix length
+// the server considers the answers valid, and whether it was present.
+func responseClientSubnet(resp *dns.Msg) (*dns.EDNS0_SUBNET, bool) {
+	opt := resp.IsEdns0()
+	if opt == nil {
+		return nil, false
+	}
+	for _, option := range opt.Option {
+		if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+			return subnet, true
+		}
+	}
+	return nil, false
+}
+
+// logClientSubnetScope emits a dnsEcsScope structured log event with the
+// scope returned by the server when we sent the edns-client-subnet option.
+func (task *Task) logClientSubnetScope(ctx context.Context, logger *slog.Logger, resp *dns.Msg) {
+	if !task.ClientSubnet.IsValid() {
+		return
+	}
+	subnet, found := responseClientSubnet(resp)
+	if !found {
+		return
+	}
+	logger.InfoContext(
+		ctx,
+		"dnsEcsScope",
+		slog.String("dnsEcsSource", task.ClientSubnet.String()),
+		slog.Int("dnsEcsScope", int(subnet.SourceScope)),
+		slog.Time("t", time.Now()),
+	)
+}
+
+// newQuery creates the query to send using the given protocol.
+//
+// When using DoT or DoH, we set the DO bit and we ask for block-length
+// padding, to avoid leaking the length of the query and response.
+func (task *Task) newQuery(protocol dnscore.Protocol, qtype uint16) (*dns.Msg, error) {
+	flags := 0
+	if protocol == dnscore.ProtocolDoT || protocol == dnscore.ProtocolDoH {
+		flags |= dnscore.EDNS0FlagDO | dnscore.EDNS0FlagBlockLengthPadding
+	}
+	optEDNS0 := dnscore.QueryOptionEDNS0(task.ednsBufferSize(protocol), flags)
+	optRD := queryOptionRecursionDesired(!task.NoRecursion)
+	optCD := queryOptionCheckingDisabled(task.CheckingDisabled)
+	optEDNS0Flags := queryOptionEDNS0Flags(task.EDNSFlags)
+	options := []dnscore.QueryOption{optRD, optCD, optEDNS0, optEDNS0Flags}
+	if task.QueryID != nil {
+		options = append(options, queryOptionID(*task.QueryID))
+	}
+	if task.TCPKeepalive && (protocol == dnscore.ProtocolTCP || protocol == dnscore.ProtocolDoT) {
+		options = append(options, queryOptionEDNS0TCPKeepalive(0))
+	}
+	if task.ClientSubnet.IsValid() {
+		options = append(options, queryOptionEDNS0ClientSubnet(task.ClientSubnet))
+	}
+	return dnscore.NewQuery(task.Name, qtype, options...)
+}
+
+// writeQuery writes the query to the QueryWriter and the query
+// ID to the QueryIDWriter. Unless the QueryIDWriter is [io.Discard],
+// we also log the query ID using a dnsQueryId structured log event.
+func (task *Task) writeQuery(ctx context.Context, logger *slog.Logger, query *dns.Msg) {
+	fmt.Fprintf(task.QueryWriter, ";; Query:\n%s\n", query.String())
+	if task.QueryIDWriter != io.Discard {
+		fmt.Fprintf(task.QueryIDWriter, ";; Query ID: %d\n", query.Id)
+		logger.InfoContext(
+			ctx,
+			"dnsQueryId",
+			slog.Int("dnsQueryId", int(query.Id)),
+			slog.Time("t", time.Now()),
+		)
+	}
+}
+
+// queryOptionID returns a [dnscore.QueryOption] that sets the
+// query ID to the given value rather than using a random value.
+func queryOptionID(id uint16) dnscore.QueryOption {
+	return func(query *dns.Msg) error {
+		query.Id = id
+		return nil
+	}
+}
+
+// ednsBufferSize returns the EDNS0 UDP payload size to advertise when
+// using the given protocol, which is the EDNSBufferSize, if set, or
+// otherwise the size suggested by [dnscore] for the protocol.
+func (task *Task) ednsBufferSize(protocol dnscore.Protocol) uint16 {
+	switch {
+	case task.EDNSBufferSize > 0:
+		return task.EDNSBufferSize
+	case protocol == dnscore.ProtocolUDP:
+		return dnscore.EDNS0SuggestedMaxResponseSizeUDP
+	default:
+		return dnscore.EDNS0SuggestedMaxResponseSizeOtherwise
+	}
+}
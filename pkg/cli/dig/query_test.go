@@ -0,0 +1,353 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+func TestQueryOptionRecursionDesired(t *testing.T) {
+	for _, value := range []bool{true, false} {
+		query, err := dnscore.NewQuery("www.example.com", dns.TypeA, queryOptionRecursionDesired(value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawQuery, err := query.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		const flagRD = 1 << 0 // lowest bit of the third header byte
+		if got := rawQuery[2]&flagRD != 0; got != value {
+			t.Fatalf("expected RD=%v, got RD=%v", value, got)
+		}
+	}
+}
+
+func TestTaskCheckingDisabled(t *testing.T) {
+	for _, value := range []bool{true, false} {
+		task := newTestTask()
+		task.CheckingDisabled = value
+		query, err := task.newQuery(dnscore.ProtocolUDP, dns.TypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawQuery, err := query.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		const flagCD = 1 << 4 // fifth lowest bit of the fourth header byte
+		if got := rawQuery[3]&flagCD != 0; got != value {
+			t.Fatalf("expected CD=%v, got CD=%v", value, got)
+		}
+	}
+}
+
+func TestParseEDNSFlags(t *testing.T) {
+	cases := []struct {
+		value   string
+		expect  uint16
+		failure bool
+	}{
+		{value: "do", expect: 0x8000},
+		{value: "do,co", expect: 0xc000},
+		{value: "DO, co", expect: 0xc000},
+		{value: "0x0001", expect: 0x0001},
+		{value: "do,0x1", expect: 0x8001},
+		{value: "nope", failure: true},
+		{value: "0x10000", failure: true},
+		{value: "0xzz", failure: true},
+		{value: "", failure: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := parseEDNSFlags(tc.value)
+			if tc.failure {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expect {
+				t.Fatalf("expected %#04x, got %#04x", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestQueryOptionEDNS0Flags(t *testing.T) {
+	t.Run("with an OPT record", func(t *testing.T) {
+		flags, err := parseEDNSFlags("do")
+		if err != nil {
+			t.Fatal(err)
+		}
+		query, err := dnscore.NewQuery("www.example.com", dns.TypeA,
+			dnscore.QueryOptionEDNS0(dnscore.EDNS0SuggestedMaxResponseSizeUDP, 0),
+			queryOptionEDNS0Flags(flags))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawQuery, err := query.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The OPT record without options is the last record and its
+		// flags field precedes the two bytes containing the RDLENGTH
+		size := len(rawQuery)
+		if got := uint16(rawQuery[size-4])<<8 | uint16(rawQuery[size-3]); got != 0x8000 {
+			t.Fatalf("expected z-field %#04x, got %#04x", 0x8000, got)
+		}
+	})
+
+	t.Run("without an OPT record", func(t *testing.T) {
+		_, err := dnscore.NewQuery("www.example.com", dns.TypeA, queryOptionEDNS0Flags(0))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestTaskEDNSBufferSize(t *testing.T) {
+	cases := []struct {
+		protocol dnscore.Protocol
+		bufsize  uint16
+		expect   uint16
+	}{
+		{protocol: dnscore.ProtocolUDP, expect: dnscore.EDNS0SuggestedMaxResponseSizeUDP},
+		{protocol: dnscore.ProtocolTCP, expect: dnscore.EDNS0SuggestedMaxResponseSizeOtherwise},
+		{protocol: dnscore.ProtocolUDP, bufsize: 512, expect: 512},
+		{protocol: dnscore.ProtocolTCP, bufsize: 512, expect: 512},
+		{protocol: dnscore.ProtocolDoH, bufsize: 4096, expect: 4096},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s/%d", tc.protocol, tc.bufsize), func(t *testing.T) {
+			task := newTestTask()
+			task.EDNSBufferSize = tc.bufsize
+			query, err := dnscore.NewQuery("www.example.com", dns.TypeA,
+				dnscore.QueryOptionEDNS0(task.ednsBufferSize(tc.protocol), 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := query.IsEdns0().UDPSize(); got != tc.expect {
+				t.Fatalf("expected %d, got %d", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestQueryOptionEDNS0TCPKeepalive(t *testing.T) {
+	t.Run("the option is packed into the OPT record", func(t *testing.T) {
+		for _, protocol := range []dnscore.Protocol{dnscore.ProtocolTCP, dnscore.ProtocolDoT} {
+			task := newTestTask()
+			task.TCPKeepalive = true
+			query, err := task.newQuery(protocol, dns.TypeA)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rawQuery, err := query.Pack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed := &dns.Msg{}
+			if err := parsed.Unpack(rawQuery); err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, option := range parsed.IsEdns0().Option {
+				_, ok := option.(*dns.EDNS0_TCP_KEEPALIVE)
+				found = found || ok
+			}
+			if !found {
+				t.Fatalf("%s: expected edns-tcp-keepalive option", protocol)
+			}
+		}
+	})
+
+	t.Run("the option is not sent over UDP", func(t *testing.T) {
+		task := newTestTask()
+		task.TCPKeepalive = true
+		query, err := task.newQuery(dnscore.ProtocolUDP, dns.TypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if options := query.IsEdns0().Option; len(options) != 0 {
+			t.Fatalf("unexpected options: %v", options)
+		}
+	})
+
+	t.Run("without an OPT record", func(t *testing.T) {
+		_, err := dnscore.NewQuery("www.example.com", dns.TypeA, queryOptionEDNS0TCPKeepalive(0))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestResponseTCPKeepalive(t *testing.T) {
+	newResponse := func(options ...dns.EDNS0) *dns.Msg {
+		resp := &dns.Msg{}
+		resp.SetReply(newTestQuery(t))
+		resp.SetEdns0(dnscore.EDNS0SuggestedMaxResponseSizeOtherwise, false)
+		resp.IsEdns0().Option = options
+		rawResp, err := resp.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed := &dns.Msg{}
+		if err := parsed.Unpack(rawResp); err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	t.Run("with a timeout", func(t *testing.T) {
+		resp := newResponse(&dns.EDNS0_TCP_KEEPALIVE{Code: dns.EDNS0TCPKEEPALIVE, Timeout: 150})
+		timeout, ok := responseTCPKeepalive(resp)
+		if !ok || timeout != 15*time.Second {
+			t.Fatalf("unexpected result: %v, %v", timeout, ok)
+		}
+	})
+
+	t.Run("without the option", func(t *testing.T) {
+		if _, ok := responseTCPKeepalive(newResponse()); ok {
+			t.Fatal("expected no timeout")
+		}
+	})
+
+	t.Run("without an OPT record", func(t *testing.T) {
+		resp := &dns.Msg{}
+		if _, ok := responseTCPKeepalive(resp); ok {
+			t.Fatal("expected no timeout")
+		}
+	})
+}
+
+func TestTaskQueryID(t *testing.T) {
+	var logs, out strings.Builder
+	id := uint16(0x1234)
+	task := newTestTask()
+	task.QueryID = &id
+	task.QueryIDWriter = &out
+	query, err := task.newQuery(dnscore.ProtocolUDP, dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawQuery, err := query.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rawQuery[0] != 0x12 || rawQuery[1] != 0x34 {
+		t.Fatalf("unexpected packed query ID: %#02x%02x", rawQuery[0], rawQuery[1])
+	}
+
+	task.writeQuery(context.Background(), newTestLogger(&logs), query)
+	if got := out.String(); got != ";; Query ID: 4660\n" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+	if !strings.Contains(logs.String(), `"msg":"dnsQueryId","dnsQueryId":4660`) {
+		t.Fatalf("unexpected logs: %q", logs.String())
+	}
+}
+
+func TestParseClientSubnet(t *testing.T) {
+	cases := []struct {
+		value   string
+		expect  string
+		failure bool
+	}{
+		{value: "203.0.113.7/24", expect: "203.0.113.0/24"},
+		{value: "203.0.113.7", expect: "203.0.113.7/32"},
+		{value: "2001:db8::1/56", expect: "2001:db8::/56"},
+		{value: "example.com", failure: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			prefix, err := parseClientSubnet(tc.value)
+			if tc.failure {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if prefix.String() != tc.expect {
+				t.Fatalf("expected %s, got %s", tc.expect, prefix)
+			}
+		})
+	}
+}
+
+func TestTaskClientSubnetScope(t *testing.T) {
+	// Create a query with the client subnet option and a response
+	// carrying the same option with a non-zero scope
+	task := newTestTask()
+	task.ClientSubnet = netip.MustParsePrefix("203.0.113.0/24")
+	query, err := task.newQuery(dnscore.ProtocolUDP, dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subnet, ok := responseClientSubnet(query)
+	if !ok || subnet.Family != 1 || subnet.SourceNetmask != 24 || subnet.Address.String() != "203.0.113.0" {
+		t.Fatalf("unexpected query option: %v", subnet)
+	}
+	resp := &dns.Msg{}
+	resp.SetReply(query)
+	resp.SetEdns0(dnscore.EDNS0SuggestedMaxResponseSizeOtherwise, false)
+	resp.IsEdns0().Option = []dns.EDNS0{&dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        1,
+		SourceNetmask: 24,
+		SourceScope:   20,
+		Address:       subnet.Address,
+	}}
+
+	t.Run("we print the scope", func(t *testing.T) {
+		var out strings.Builder
+		task.ResponseWriter = &out
+		task.streamResponse(newTestServerAddr(), resp, nil)
+		if !strings.Contains(out.String(), ";; Client subnet scope: /20 (source 203.0.113.0/24)") {
+			t.Fatalf("unexpected output: %q", out.String())
+		}
+	})
+
+	t.Run("we log the scope", func(t *testing.T) {
+		var logs strings.Builder
+		task.logClientSubnetScope(context.Background(), newTestLogger(&logs), resp)
+		var ev struct {
+			Msg    string `json:"msg"`
+			Source string `json:"dnsEcsSource"`
+			Scope  int    `json:"dnsEcsScope"`
+		}
+		if err := json.Unmarshal([]byte(logs.String()), &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Msg != "dnsEcsScope" || ev.Source != "203.0.113.0/24" || ev.Scope != 20 {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	})
+
+	t.Run("we do not log without the option in the query", func(t *testing.T) {
+		var logs strings.Builder
+		task := newTestTask()
+		task.logClientSubnetScope(context.Background(), newTestLogger(&logs), resp)
+		if logs.Len() > 0 {
+			t.Fatalf("unexpected logs: %s", logs.String())
+		}
+	})
+}
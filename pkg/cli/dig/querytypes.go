@@ -0,0 +1,18 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// writeQueryTypes writes the supported query types, sorted
+// alphabetically, along with their description.
+func writeQueryTypes(w io.Writer) {
+	for _, name := range slices.Sorted(maps.Keys(queryTypeMap)) {
+		fmt.Fprintf(w, "%-8s %s\n", name, queryTypeMap[name].Description)
+	}
+}
@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteQueryTypes(t *testing.T) {
+	var out strings.Builder
+	writeQueryTypes(&out)
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(queryTypeMap) {
+		t.Fatalf("expected %d lines, got %d", len(queryTypeMap), len(lines))
+	}
+	for _, name := range []string{"A", "AAAA", "HINFO", "LOC"} {
+		expect := name + " "
+		found := false
+		for _, line := range lines {
+			found = found || strings.HasPrefix(line, expect)
+		}
+		if !found {
+			t.Fatalf("missing %s in %q", name, out.String())
+		}
+	}
+	if !strings.HasPrefix(lines[0], "A ") || !strings.HasPrefix(lines[1], "AAAA ") {
+		t.Fatalf("expected sorted output, got %q", out.String())
+	}
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/rbmk-project/common/fsx"
+	"github.com/rbmk-project/dnscore"
+	"github.com/rbmk-project/x/netcore"
+)
+
+// maxRawMessageSize is the maximum size of a raw DNS message.
+const maxRawMessageSize = 65535
+
+// loadRawQuery reads the raw query bytes from the given file, failing if
+// the file is empty or larger than the maximum DNS message size.
+func loadRawQuery(fsys fsx.FS, path string) ([]byte, error) {
+	data, err := readFile(fsys, path, maxRawMessageSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= 0 {
+		return nil, fmt.Errorf("empty raw query file: %s", path)
+	}
+	return data, nil
+}
+
+// rawExchange sends the RawQuery bytes to the ServerAddr using the given
+// protocol, writes a hex dump of the raw response to the ResponseWriter,
+// and returns the raw response, without parsing or validating it.
+//
+// When using TCP or DoT, we frame the query by prefixing it with its
+// length; when using DoH, we POST it as an application/dns-message.
+//
+// Since we bypass the [dnscore.Transport], we emit ourselves the dnsQuery
+// and dnsResponse structured log events it would emit, including the raw
+// query and response bytes (see logRawQuery and logRawResponse).
+func (task *Task) rawExchange(ctx context.Context, logger *slog.Logger,
+	netx *netcore.Network, client *http.Client, protocol dnscore.Protocol) ([]byte, error) {
+	address := task.newServerAddr(protocol, task.ServerAddr)
+	server := dnscore.NewServerAddr(protocol, address)
+	var (
+		rawResp      []byte
+		laddr, raddr net.Addr
+		err          error
+	)
+	t0 := logRawQuery(ctx, logger, server, task.RawQuery)
+	switch protocol {
+	case dnscore.ProtocolUDP:
+		rawResp, laddr, raddr, err = task.rawExchangeDatagram(ctx, netx.DialContext, address)
+	case dnscore.ProtocolTCP:
+		rawResp, laddr, raddr, err = task.rawExchangeStream(ctx, netx.DialContext, address)
+	case dnscore.ProtocolDoT:
+		rawResp, laddr, raddr, err = task.rawExchangeStream(ctx, netx.DialTLSContext, address)
+	case dnscore.ProtocolDoH:
+		rawResp, err = task.rawExchangeHTTPS(ctx, client, address)
+	default:
+		err = fmt.Errorf("unsupported protocol: %s", protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	logRawResponse(ctx, logger, server, t0, task.RawQuery, rawResp, laddr, raddr)
+	fmt.Fprintf(task.ResponseWriter, ";; Raw response (%d bytes):\n%s\n", len(rawResp), hex.Dump(rawResp))
+	return rawResp, nil
+}
+
+// rawExchangeDatagram sends the raw query as a single UDP datagram and
+// returns the raw response along with the local and remote addresses.
+func (task *Task) rawExchangeDatagram(ctx context.Context,
+	dial func(ctx context.Context, network, address string) (net.Conn, error),
+	address string) ([]byte, net.Addr, net.Addr, error) {
+	conn, err := dial(ctx, "udp", address)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(task.RawQuery); err != nil {
+		return nil, nil, nil, err
+	}
+	buffer := make([]byte, maxRawMessageSize)
+	count, err := conn.Read(buffer)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return buffer[:count], conn.LocalAddr(), conn.RemoteAddr(), nil
+}
+
+// rawExchangeStream sends the raw query prefixed by its length over a stream
+// and returns the raw response along with the local and remote addresses.
+func (task *Task) rawExchangeStream(ctx context.Context,
+	dial func(ctx context.Context, network, address string) (net.Conn, error),
+	address string) ([]byte, net.Addr, net.Addr, error) {
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	frame := binary.BigEndian.AppendUint16(nil, uint16(len(task.RawQuery)))
+	frame = append(frame, task.RawQuery...)
+	if _, err := conn.Write(frame); err != nil {
+		return nil, nil, nil, err
+	}
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, nil, nil, err
+	}
+	rawResp := make([]byte, binary.BigEndian.Uint16(header))
+	if _, err := io.ReadFull(conn, rawResp); err != nil {
+		return nil, nil, nil, err
+	}
+	return rawResp, conn.LocalAddr(), conn.RemoteAddr(), nil
+}
+
+// rawExchangeHTTPS sends the raw query as the body of a DoH POST request.
+func (task *Task) rawExchangeHTTPS(ctx context.Context, client *http.Client, URL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", URL, bytes.NewReader(task.RawQuery))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("content-type", "application/dns-message")
+	req.Header.Set("accept", "application/dns-message")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %d", resp.StatusCode)
+	}
+	rawResp, err := io.ReadAll(io.LimitReader(resp.Body, maxRawMessageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(rawResp) > maxRawMessageSize {
+		return nil, errors.New("response too large")
+	}
+	return rawResp, nil
+}
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rbmk-project/common/fsx"
+)
+
+// testRawQuery is a raw query for example.com IN A with ID 0x1234.
+var testRawQuery = []byte{
+	0x12, 0x34, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x07, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0x03, 'c', 'o', 'm', 0x00,
+	0x00, 0x01, 0x00, 0x01,
+}
+
+func TestLoadRawQuery(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("with a valid file", func(t *testing.T) {
+		path := filepath.Join(dir, "query.bin")
+		if err := os.WriteFile(path, testRawQuery, 0600); err != nil {
+			t.Fatal(err)
+		}
+		data, err := loadRawQuery(fsx.OsFS{}, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(testRawQuery) {
+			t.Fatalf("unexpected data: %x", data)
+		}
+	})
+
+	t.Run("with an empty file", func(t *testing.T) {
+		path := filepath.Join(dir, "empty.bin")
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadRawQuery(fsx.OsFS{}, path); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestTaskRunRawQuery(t *testing.T) {
+	// the echoed query header must appear at the beginning of the dump
+	const expectDump = "00000000  12 34 01 00 00 01 00 00  00 00 00 00 07 65 78 61"
+
+	t.Run("over UDP", func(t *testing.T) {
+		pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pconn.Close()
+		go func() {
+			buffer := make([]byte, 1024)
+			count, addr, err := pconn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			pconn.WriteTo(buffer[:count], addr)
+		}()
+
+		var logs, out strings.Builder
+		task := newTestTask()
+		task.LogsWriter = &logs
+		task.RawQuery = testRawQuery
+		task.ResponseWriter = &out
+		task.ServerAddr, task.ServerPort, _ = net.SplitHostPort(pconn.LocalAddr().String())
+		if err := task.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), ";; Raw response (29 bytes):\n"+expectDump) {
+			t.Fatalf("unexpected output: %q", out.String())
+		}
+
+		// We emit the same events that the dnscore transport would emit
+		var events []string
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var ev struct {
+				Msg            string `json:"msg"`
+				DNSRawQuery    []byte `json:"dnsRawQuery"`
+				DNSRawResponse []byte `json:"dnsRawResponse"`
+				RemoteAddr     string `json:"remoteAddr"`
+				ServerProtocol string `json:"serverProtocol"`
+			}
+			if err := json.Unmarshal([]byte(line), &ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Msg != "dnsQuery" && ev.Msg != "dnsResponse" {
+				continue
+			}
+			events = append(events, ev.Msg)
+			if !bytes.Equal(ev.DNSRawQuery, testRawQuery) || ev.ServerProtocol != "udp" {
+				t.Fatalf("unexpected %s event: %+v", ev.Msg, ev)
+			}
+			if ev.Msg == "dnsResponse" &&
+				(!bytes.Equal(ev.DNSRawResponse, testRawQuery) || ev.RemoteAddr != pconn.LocalAddr().String()) {
+				t.Fatalf("unexpected %s event: %+v", ev.Msg, ev)
+			}
+		}
+		if strings.Join(events, ",") != "dnsQuery,dnsResponse" {
+			t.Fatalf("unexpected events: %v", events)
+		}
+	})
+
+	t.Run("over TCP", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			body := make([]byte, binary.BigEndian.Uint16(header))
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			conn.Write(append(header, body...))
+		}()
+
+		var out strings.Builder
+		task := newTestTask()
+		task.Protocol = "tcp"
+		task.RawQuery = testRawQuery
+		task.ResponseWriter = &out
+		task.ServerAddr, task.ServerPort, _ = net.SplitHostPort(listener.Addr().String())
+		if err := task.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), ";; Raw response (29 bytes):\n"+expectDump) {
+			t.Fatalf("unexpected output: %q", out.String())
+		}
+	})
+}
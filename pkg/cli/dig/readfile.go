@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rbmk-project/common/fsx"
+)
+
+// maxInputFileSize is the maximum size of the input files we read.
+const maxInputFileSize = 1 << 20
+
+// errFileTooLarge indicates that a file is larger than we can accept.
+var errFileTooLarge = errors.New("file too large")
+
+// readFile reads the whole content of the given file, failing
+// with [errFileTooLarge] if the file is larger than maxBytes, to
+// avoid exhausting the memory when reading a huge file.
+func readFile(fsys fsx.FS, path string, maxBytes int64) ([]byte, error) {
+	filep, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer filep.Close()
+	data, err := io.ReadAll(io.LimitReader(filep, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("%w: %s", errFileTooLarge, path)
+	}
+	return data, nil
+}
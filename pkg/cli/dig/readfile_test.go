@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbmk-project/common/fsx"
+)
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(path, []byte("0123456789"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		maxBytes int64
+		err      error
+	}{
+		{name: "under the limit", maxBytes: 11, err: nil},
+		{name: "at the limit", maxBytes: 10, err: nil},
+		{name: "over the limit", maxBytes: 9, err: errFileTooLarge},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := readFile(fsx.OsFS{}, path, tc.maxBytes)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+			if err == nil && string(data) != "0123456789" {
+				t.Fatalf("unexpected data: %q", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// repeatUntilChange sends the query every RepeatInterval until the
+// response differs from the first response or we have sent RepeatMax
+// queries, whichever comes first. On change, we write the differences
+// between the first and the changed response to the DiffWriter.
+//
+// A failed query does not stop repeating: we write the failure to the
+// DiffWriter, continue with the next query, and compare with the first
+// response we actually received. We return the last response we received
+// along with the errors of all the failed queries joined together, if any.
+// When the context is done, we stop repeating, report the outcome of the
+// queries sent so far, and also include the context error.
+// We emit a dnsRepeatSummary structured log event describing the outcome.
+func (task *Task) repeatUntilChange(
+	ctx context.Context,
+	logger *slog.Logger,
+	txp dnsTransport,
+	protocol dnscore.Protocol,
+	query *dns.Msg,
+) (*dns.Msg, error) {
+	var (
+		changed     bool
+		errv        []error
+		first, last *dns.Msg
+		firstIndex  int
+		interrupted bool
+		iteration   int
+		t0          = time.Now()
+	)
+	for iteration < task.RepeatMax && !changed {
+		if iteration > 0 {
+			select {
+			case <-ctx.Done():
+				interrupted = true
+			case <-time.After(task.RepeatInterval):
+			}
+		}
+		if interrupted {
+			errv = append(errv, ctx.Err())
+			break
+		}
+		iteration++
+		resp, err := task.exchangeInOrder(ctx, txp, protocol, query)
+		if err != nil {
+			fmt.Fprintf(task.DiffWriter, "\n;; Query #%d failed: %s\n", iteration, err.Error())
+			errv = append(errv, fmt.Errorf("query #%d: %w", iteration, err))
+			continue
+		}
+		last = resp
+		if first == nil {
+			first, firstIndex = resp, iteration
+			continue
+		}
+		if diff := diffResponses(first, resp); !diff.Equal() {
+			label := fmt.Sprintf("response #%d vs response #%d", firstIndex, iteration)
+			diff.write(task.DiffWriter, label)
+			changed = true
+		}
+	}
+	switch {
+	case first == nil:
+		fmt.Fprintf(task.DiffWriter, "\n;; No response after %d queries\n", iteration)
+	case !changed:
+		fmt.Fprintf(task.DiffWriter, "\n;; No change after %d queries\n", iteration)
+	}
+	logger.InfoContext(
+		ctx,
+		"dnsRepeatSummary",
+		slog.Bool("changed", changed),
+		slog.Int("failures", len(errv)),
+		slog.Bool("interrupted", interrupted),
+		slog.Int("queries", iteration),
+		slog.Time("t0", t0),
+		slog.Time("t", time.Now()),
+	)
+	return last, errors.Join(errv...)
+}
@@ -0,0 +1,237 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// changingTransport is a [dnsTransport] whose answer changes starting
+// from the given call, to simulate intermittent poisoning, and which
+// fails the calls listed in failAt, to simulate intermittent failures.
+type changingTransport struct {
+	mockTransport
+	calls      int
+	changeAt   int
+	changedRRs []dns.RR
+	failAt     []int
+}
+
+// Query implements [dnsTransport].
+func (txp *changingTransport) Query(
+	ctx context.Context, addr *dnscore.ServerAddr, query *dns.Msg) (*dns.Msg, error) {
+	txp.calls++
+	if slices.Contains(txp.failAt, txp.calls) {
+		return nil, errors.New("mocked intermittent error")
+	}
+	resp, err := txp.mockTransport.Query(ctx, addr, query)
+	if err == nil && txp.calls >= txp.changeAt {
+		resp.Answer = txp.changedRRs
+	}
+	return resp, err
+}
+
+func TestTaskRepeatUntilChange(t *testing.T) {
+	newTransport := func() *changingTransport {
+		return &changingTransport{
+			mockTransport: mockTransport{
+				responses: map[string][]dns.RR{
+					"8.8.8.8:53": {newTestA("93.184.216.34")},
+				},
+			},
+			changeAt:   3,
+			changedRRs: []dns.RR{newTestA("10.10.34.35")},
+		}
+	}
+
+	// parseSummary parses the dnsRepeatSummary event from the logs.
+	parseSummary := func(t *testing.T, logs string) (changed bool, queries int) {
+		var ev struct {
+			Msg     string `json:"msg"`
+			Changed bool   `json:"changed"`
+			Queries int    `json:"queries"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(logs)), &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Msg != "dnsRepeatSummary" {
+			t.Fatalf("unexpected event: %s", ev.Msg)
+		}
+		return ev.Changed, ev.Queries
+	}
+
+	// parseFailures parses the failures of the dnsRepeatSummary event.
+	parseFailures := func(t *testing.T, logs string) int {
+		var ev struct {
+			Failures int `json:"failures"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(logs)), &ev); err != nil {
+			t.Fatal(err)
+		}
+		return ev.Failures
+	}
+
+	t.Run("we stop when the answers change", func(t *testing.T) {
+		var diff, logs strings.Builder
+		txp := newTransport()
+		task := newTestTask()
+		task.DiffWriter = &diff
+		task.RepeatInterval = time.Millisecond
+		task.RepeatMax = 10
+		resp, err := task.repeatUntilChange(context.Background(),
+			newTestLogger(&logs), txp, dnscore.ProtocolUDP, newTestQuery(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Answer[0].(*dns.A).A.String(); got != "10.10.34.35" {
+			t.Fatalf("unexpected answer: %s", got)
+		}
+		if txp.calls != 3 {
+			t.Fatalf("expected 3 queries, got %d", txp.calls)
+		}
+		for _, expect := range []string{
+			";; Diff: response #1 vs response #3",
+			"-www.example.com.\tIN\tA\t93.184.216.34",
+			"+www.example.com.\tIN\tA\t10.10.34.35",
+		} {
+			if !strings.Contains(diff.String(), expect) {
+				t.Fatalf("expected %q in %q", expect, diff.String())
+			}
+		}
+		if changed, queries := parseSummary(t, logs.String()); !changed || queries != 3 {
+			t.Fatalf("unexpected summary: changed=%v queries=%d", changed, queries)
+		}
+	})
+
+	t.Run("we stop after RepeatMax queries", func(t *testing.T) {
+		var diff, logs strings.Builder
+		txp := newTransport()
+		task := newTestTask()
+		task.DiffWriter = &diff
+		task.RepeatInterval = time.Millisecond
+		task.RepeatMax = 2
+		if _, err := task.repeatUntilChange(context.Background(),
+			newTestLogger(&logs), txp, dnscore.ProtocolUDP, newTestQuery(t)); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(diff.String(), ";; No change after 2 queries") {
+			t.Fatalf("unexpected diff output: %q", diff.String())
+		}
+		if changed, queries := parseSummary(t, logs.String()); changed || queries != 2 {
+			t.Fatalf("unexpected summary: changed=%v queries=%d", changed, queries)
+		}
+	})
+
+	t.Run("a failure in the middle does not stop repeating", func(t *testing.T) {
+		var diff, logs strings.Builder
+		txp := newTransport()
+		txp.changeAt = 4
+		txp.failAt = []int{2}
+		task := newTestTask()
+		task.DiffWriter = &diff
+		task.RepeatInterval = time.Millisecond
+		task.RepeatMax = 10
+		resp, err := task.repeatUntilChange(context.Background(),
+			newTestLogger(&logs), txp, dnscore.ProtocolUDP, newTestQuery(t))
+		if err == nil || !strings.Contains(err.Error(), "query #2: 8.8.8.8: mocked intermittent error") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp == nil || resp.Answer[0].(*dns.A).A.String() != "10.10.34.35" {
+			t.Fatalf("unexpected response: %v", resp)
+		}
+		if txp.calls != 4 {
+			t.Fatalf("expected 4 queries, got %d", txp.calls)
+		}
+		for _, expect := range []string{
+			";; Query #2 failed: ",
+			";; Diff: response #1 vs response #4",
+		} {
+			if !strings.Contains(diff.String(), expect) {
+				t.Fatalf("expected %q in %q", expect, diff.String())
+			}
+		}
+		if changed, queries := parseSummary(t, logs.String()); !changed || queries != 4 {
+			t.Fatalf("unexpected summary: changed=%v queries=%d", changed, queries)
+		}
+		if failures := parseFailures(t, logs.String()); failures != 1 {
+			t.Fatalf("expected 1 failure, got %d", failures)
+		}
+	})
+
+	t.Run("we compare with the first successful response", func(t *testing.T) {
+		var diff strings.Builder
+		txp := newTransport()
+		txp.changeAt = 100
+		txp.failAt = []int{1}
+		task := newTestTask()
+		task.DiffWriter = &diff
+		task.RepeatInterval = time.Millisecond
+		task.RepeatMax = 3
+		resp, err := task.repeatUntilChange(context.Background(),
+			newTestLogger(&strings.Builder{}), txp, dnscore.ProtocolUDP, newTestQuery(t))
+		if err == nil || resp == nil {
+			t.Fatalf("expected both a response and an error, got %v, %v", resp, err)
+		}
+		if !strings.Contains(diff.String(), ";; No change after 3 queries") {
+			t.Fatalf("unexpected diff output: %q", diff.String())
+		}
+	})
+
+	t.Run("we do not report no change when all the queries fail", func(t *testing.T) {
+		var diff, logs strings.Builder
+		txp := newTransport()
+		txp.failAt = []int{1, 2, 3}
+		task := newTestTask()
+		task.DiffWriter = &diff
+		task.RepeatInterval = time.Millisecond
+		task.RepeatMax = 3
+		resp, err := task.repeatUntilChange(context.Background(),
+			newTestLogger(&logs), txp, dnscore.ProtocolUDP, newTestQuery(t))
+		if err == nil || resp != nil {
+			t.Fatalf("expected only an error, got %v, %v", resp, err)
+		}
+		if strings.Contains(diff.String(), ";; No change") ||
+			!strings.Contains(diff.String(), ";; No response after 3 queries") {
+			t.Fatalf("unexpected diff output: %q", diff.String())
+		}
+		if changed, queries := parseSummary(t, logs.String()); changed || queries != 3 {
+			t.Fatalf("unexpected summary: changed=%v queries=%d", changed, queries)
+		}
+		if failures := parseFailures(t, logs.String()); failures != 3 {
+			t.Fatalf("expected 3 failures, got %d", failures)
+		}
+	})
+
+	t.Run("we honor context cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		var diff, logs strings.Builder
+		task := newTestTask()
+		task.DiffWriter = &diff
+		task.RepeatInterval = time.Hour
+		task.RepeatMax = 10
+		resp, err := task.repeatUntilChange(ctx,
+			newTestLogger(&logs), newTransport(), dnscore.ProtocolUDP, newTestQuery(t))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp == nil {
+			t.Fatal("expected the response received before the cancellation")
+		}
+		if !strings.Contains(diff.String(), ";; No change after 1 queries") {
+			t.Fatalf("unexpected diff output: %q", diff.String())
+		}
+		if changed, queries := parseSummary(t, logs.String()); changed || queries != 1 {
+			t.Fatalf("unexpected summary: changed=%v queries=%d", changed, queries)
+		}
+	})
+}
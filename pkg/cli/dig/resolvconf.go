@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/rbmk-project/common/fsx"
+)
+
+// resolvConfPath is the path of the system resolver configuration.
+//
+// Note that this file only exists on Unix-like systems.
+const resolvConfPath = "/etc/resolv.conf"
+
+// loadResolvConf reads the resolv.conf file at the given path
+// and returns the nameserver addresses in the order in which
+// they appear, or an error if there are no nameservers.
+func loadResolvConf(fsys fsx.FS, path string) ([]string, error) {
+	data, err := readFile(fsys, path, maxInputFileSize)
+	if err != nil {
+		return nil, err
+	}
+	servers := parseResolvConf(data)
+	if len(servers) <= 0 {
+		return nil, fmt.Errorf("no nameserver entries in %s", path)
+	}
+	return servers, nil
+}
+
+// parseResolvConf parses the content of a resolv.conf file and returns
+// the nameserver addresses in order, skipping comments and entries that
+// are not valid IP addresses. See resolv.conf(5) for the format.
+func parseResolvConf(data []byte) []string {
+	var servers []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		addr, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			continue
+		}
+		servers = append(servers, addr.String())
+	}
+	return servers
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/rbmk-project/common/fsx"
+)
+
+// sampleResolvConf is a sample resolv.conf content.
+const sampleResolvConf = `# Generated by NetworkManager
+search example.com
+; another comment style
+nameserver 192.168.1.1
+nameserver   2001:db8::53
+nameserver fe80::1%eth0
+nameserver not-an-address
+nameserver
+options edns0 trust-ad
+nameserver 9.9.9.9 # trailing comment
+`
+
+func TestParseResolvConf(t *testing.T) {
+	expect := []string{"192.168.1.1", "2001:db8::53", "fe80::1%eth0", "9.9.9.9"}
+	if got := parseResolvConf([]byte(sampleResolvConf)); !slices.Equal(got, expect) {
+		t.Fatalf("expected %v, got %v", expect, got)
+	}
+}
+
+func TestLoadResolvConf(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("with nameserver entries", func(t *testing.T) {
+		path := filepath.Join(dir, "resolv.conf")
+		if err := os.WriteFile(path, []byte(sampleResolvConf), 0600); err != nil {
+			t.Fatal(err)
+		}
+		servers, err := loadResolvConf(fsx.OsFS{}, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(servers) != 4 || servers[0] != "192.168.1.1" {
+			t.Fatalf("unexpected servers: %v", servers)
+		}
+	})
+
+	t.Run("without nameserver entries", func(t *testing.T) {
+		path := filepath.Join(dir, "empty.conf")
+		if err := os.WriteFile(path, []byte("search example.com\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadResolvConf(fsx.OsFS{}, path); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("with nonexistent file", func(t *testing.T) {
+		if _, err := loadResolvConf(fsx.OsFS{}, filepath.Join(dir, "nonexistent")); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// defaultServerPorts maps each protocol to its default server port.
+var defaultServerPorts = map[dnscore.Protocol]string{
+	dnscore.ProtocolUDP: "53",
+	dnscore.ProtocolTCP: "53",
+	dnscore.ProtocolDoT: "853",
+	dnscore.ProtocolDoH: "443",
+}
+
+// retryProtocols tries each of the RetryProtocols in order, using the
+// default port of each protocol, until one of them yields a valid response,
+// and returns the query and the response, or the errors of all the attempts.
+//
+// We emit a dnsProtocolAttempt structured log event for each attempt.
+func (task *Task) retryProtocols(
+	ctx context.Context,
+	logger *slog.Logger,
+	txp dnsTransport,
+	qtype uint16,
+) (*dns.Msg, *dns.Msg, error) {
+	var errv []error
+	for _, name := range task.RetryProtocols {
+		protocol, err := parseProtocol(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		attempt := *task
+		attempt.ServerPort = defaultServerPorts[protocol]
+		attempt.WaitDuplicates = false
+
+		query, err := attempt.newQuery(protocol, qtype)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot create query: %w", err)
+		}
+		attempt.writeQuery(ctx, logger, query)
+
+		t0 := time.Now()
+		resp, err := attempt.exchangeInOrder(ctx, txp, protocol, query)
+		if err == nil {
+			err = dnscore.ValidateResponse(query, resp)
+		}
+		logger.InfoContext(
+			ctx,
+			"dnsProtocolAttempt",
+			slog.Any("err", err),
+			slog.String("serverProtocol", string(protocol)),
+			slog.Time("t0", t0),
+			slog.Time("t", time.Now()),
+		)
+		if err == nil {
+			return query, resp, nil
+		}
+		errv = append(errv, fmt.Errorf("%s: %w", protocol, err))
+	}
+	return nil, nil, errors.Join(errv...)
+}
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestTaskRetryProtocols(t *testing.T) {
+	txp := &mockTransport{
+		responses: map[string][]dns.RR{
+			"8.8.8.8:853": {newTestA("93.184.216.34")},
+		},
+	}
+
+	t.Run("we stop at the first protocol yielding a valid response", func(t *testing.T) {
+		var logs strings.Builder
+		task := newTestTask()
+		task.RetryProtocols = []string{"udp", "tcp", "dot", "doh"}
+		query, resp, err := task.retryProtocols(
+			context.Background(), newTestLogger(&logs), txp, dns.TypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query.Id != resp.Id || len(resp.Answer) != 1 {
+			t.Fatalf("unexpected response: %v", resp)
+		}
+
+		var protocols []string
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var ev struct {
+				Msg            string  `json:"msg"`
+				Err            *string `json:"err"`
+				ServerProtocol string  `json:"serverProtocol"`
+			}
+			if err := json.Unmarshal([]byte(line), &ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Msg != "dnsProtocolAttempt" {
+				t.Fatalf("unexpected event: %s", ev.Msg)
+			}
+			if failed := ev.Err != nil; failed != (ev.ServerProtocol != "dot") {
+				t.Fatalf("unexpected err for %s: %v", ev.ServerProtocol, ev.Err)
+			}
+			protocols = append(protocols, ev.ServerProtocol)
+		}
+		if got := strings.Join(protocols, ","); got != "udp,tcp,dot" {
+			t.Fatalf("unexpected attempts: %s", got)
+		}
+	})
+
+	t.Run("we return all the errors when all protocols fail", func(t *testing.T) {
+		task := newTestTask()
+		task.RetryProtocols = []string{"udp", "tcp"}
+		_, _, err := task.retryProtocols(
+			context.Background(), newTestLogger(&strings.Builder{}), txp, dns.TypeA)
+		if err == nil || !strings.Contains(err.Error(), "udp: 8.8.8.8: mocked error") ||
+			!strings.Contains(err.Error(), "tcp: 8.8.8.8: mocked error") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
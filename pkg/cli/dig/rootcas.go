@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"crypto/x509"
+	"fmt"
+
+	"github.com/rbmk-project/common/fsx"
+)
+
+// loadRootCAs returns a new [*x509.CertPool] containing the PEM
+// encoded certificates read from the files at the given paths. When
+// system is true, the pool also contains the system's root CAs.
+//
+// We fail if we cannot read a file or a file does not contain
+// at least one valid PEM encoded certificate.
+func loadRootCAs(fsys fsx.FS, system bool, paths ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if system {
+		systemPool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, err
+		}
+		pool = systemPool
+	}
+	for _, path := range paths {
+		data, err := readFile(fsys, path, maxInputFileSize)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no valid PEM certificates in %s", path)
+		}
+	}
+	return pool, nil
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbmk-project/common/fsx"
+	"github.com/rbmk-project/rbmk/internal/testable"
+	"github.com/rbmk-project/x/netsim"
+)
+
+// runDoTScenario runs `rbmk dig` with the given arguments inside a netsim
+// scenario simulating dns.google and www.example.com, using a PKI whose
+// certificates are cached inside the given directory. We do not override
+// the root CAs, such that only --ca-file allows verifying the server.
+func runDoTScenario(dir string, argv ...string) error {
+	scenario := netsim.NewScenario(dir)
+	defer scenario.Close()
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewExampleComStack())
+
+	stack := scenario.MustNewClientStack()
+	scenario.Attach(stack)
+	testable.DialContext.Set(stack.DialContext)
+	defer testable.DialContext.Set(nil)
+
+	env := testable.NewEnvironment()
+	env.SetStdout(io.Discard)
+	env.SetStderr(io.Discard)
+	argv = append([]string{"dig"}, argv...)
+	argv = append(argv, "+tls", "@8.8.8.8", "A", "www.example.com")
+	return NewCommand().Main(context.Background(), env, argv...)
+}
+
+func TestLoadRootCAs(t *testing.T) {
+	// the simulated PKI stores the self-signed certificate of each
+	// server inside a directory named after its base64 common name
+	dir := t.TempDir()
+	certfile := filepath.Join(dir, "pkistore",
+		base64.URLEncoding.EncodeToString([]byte("dns.google")), "cert.pem")
+
+	t.Run("on success with DNS over TLS", func(t *testing.T) {
+		if err := runDoTScenario(dir, "--ca-file", certfile); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("on success with DNS over TLS and the system roots", func(t *testing.T) {
+		if err := runDoTScenario(dir, "--ca-file", certfile, "--ca-system-roots"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("with DNS over TLS and only the system roots", func(t *testing.T) {
+		if err := runDoTScenario(dir); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("with --ca-system-roots and without --ca-file", func(t *testing.T) {
+		err := runDoTScenario(dir, "--ca-system-roots")
+		if err == nil || err.Error() != "--ca-system-roots requires --ca-file" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("with a nonexistent file", func(t *testing.T) {
+		_, err := loadRootCAs(fsx.OsFS{}, false, filepath.Join(dir, "nonexistent.pem"))
+		if !fsx.IsNotExist(err) {
+			t.Fatalf("expected not exist error, got %v", err)
+		}
+	})
+
+	t.Run("with a file not containing certificates", func(t *testing.T) {
+		path := filepath.Join(dir, "garbage.pem")
+		if err := os.WriteFile(path, []byte("garbage"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadRootCAs(fsx.OsFS{}, false, path)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// runMode is a way of running the task selected using a command line
+// flag (e.g., --compare). The flags selecting the modes are mutually
+// exclusive, and each mode only honours some of the other options.
+type runMode struct {
+	// Flag is the flag selecting the mode or empty for the default
+	// mode, where we send a single query and check the response.
+	Flag string
+
+	// Ignores contains the flags and the "+" options that the
+	// mode does not honour, which we reject rather than ignoring.
+	Ignores []string
+}
+
+// checkOptions contains the options we honour when checking
+// the response, which the modes not calling checkResponse ignore.
+var checkOptions = []string{
+	"--count-answers",
+	"--expect",
+	"--fail-on-bogon",
+	"--fail-on-empty",
+	"--min-ttl",
+	"--no-validate",
+}
+
+// formatOptions contains the options selecting the output format
+// (see resolveOutputFormat), which --raw-query ignores.
+var formatOptions = []string{
+	"--answers-only",
+	"--csv",
+	"--output-format",
+	"+answer",
+	"+short",
+}
+
+// queryOptions contains the options modifying the query we create,
+// which --raw-query ignores since it sends the bytes as is.
+var queryOptions = []string{
+	"--edns-flags",
+	"--norecurse",
+	"--print-query-id",
+	"--query-id",
+	"--recursive",
+	"--stub",
+	"+bufsize",
+	"+cdflag",
+	"+keepalive",
+	"+qr",
+	"+subnet",
+}
+
+// pollIgnores contains the options that polling ignores.
+var pollIgnores = slices.Concat(checkOptions, []string{"--tcp-mss"})
+
+// runModeDefault is the default [*runMode].
+var runModeDefault = &runMode{Flag: ""}
+
+// runModes contains the [*runMode] selected by flags, sorted by flag.
+var runModes = []*runMode{
+	{
+		Flag: "--compare",
+		Ignores: slices.Concat(checkOptions, []string{
+			"--doh-host",
+			"--server-from-resolv-conf",
+			"--tcp-mss",
+		}),
+	},
+	{
+		Flag: "--raw-query",
+		Ignores: slices.Concat(checkOptions, formatOptions, queryOptions, []string{
+			"--duplicates-timeout",
+			"--output-dir",
+			"--server-from-resolv-conf",
+			"--summary-json",
+			"--tcp-fallback",
+			"--tcp-mss",
+			"--wait-all-duplicates",
+		}),
+	},
+	{
+		Flag:    "--repeat-until-change",
+		Ignores: []string{"--tcp-mss"},
+	},
+	{
+		Flag: "--retry-protocols",
+		Ignores: []string{
+			"--doh-host",
+			"--duplicates-timeout",
+			"--no-validate",
+			"--protocol",
+			"--tcp-mss",
+			"--wait-all-duplicates",
+			"+https",
+			"+tcp",
+			"+tls",
+			"+udp",
+		},
+	},
+	{
+		Flag: "--servers-parallel",
+		Ignores: slices.Concat(checkOptions, []string{
+			"--doh-host",
+			"--server-from-resolv-conf",
+			"--tcp-mss",
+		}),
+	},
+	{
+		Flag: "--tcp-keepalive",
+		Ignores: []string{
+			"--output-dir",
+			"--server-from-resolv-conf",
+			"--summary-json",
+			"--tcp-mss",
+		},
+	},
+	{
+		Flag:    "--until-noerror",
+		Ignores: pollIgnores,
+	},
+	{
+		Flag:    "--until-nxdomain",
+		Ignores: pollIgnores,
+	},
+}
+
+// modeOptions maps the options configuring a specific mode
+// to the flags selecting the modes honouring them.
+var modeOptions = map[string][]string{
+	"--poll-interval": {"--until-noerror", "--until-nxdomain"},
+	"--poll-timeout":  {"--until-noerror", "--until-nxdomain"},
+	"--repeat-max":    {"--repeat-until-change"},
+}
+
+// selectRunMode returns the [*runMode] selected by the given used flags
+// and "+" options (e.g., "--compare" or "+short"), failing when they
+// select more than one mode or the mode does not honour any of them.
+func selectRunMode(used map[string]bool) (*runMode, error) {
+	mode := runModeDefault
+	for _, candidate := range runModes {
+		if !used[candidate.Flag] {
+			continue
+		}
+		if mode != runModeDefault {
+			return nil, fmt.Errorf("%s conflicts with %s", mode.Flag, candidate.Flag)
+		}
+		mode = candidate
+	}
+	for _, option := range mode.Ignores {
+		if used[option] {
+			return nil, fmt.Errorf("%s conflicts with %s", option, mode.Flag)
+		}
+	}
+	for _, option := range slices.Sorted(maps.Keys(modeOptions)) {
+		flags := modeOptions[option]
+		if used[option] && !slices.Contains(flags, mode.Flag) {
+			return nil, fmt.Errorf("%s requires %s", option, strings.Join(flags, " or "))
+		}
+	}
+	return mode, nil
+}
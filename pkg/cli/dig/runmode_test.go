@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectRunMode(t *testing.T) {
+	cases := []struct {
+		used       []string
+		expectFlag string
+		expectErr  string
+	}{
+		{used: nil, expectFlag: ""},
+		{used: []string{"--expect", "--tcp-mss", "+short"}, expectFlag: ""},
+		{used: []string{"--compare", "+short", "+tcp"}, expectFlag: "--compare"},
+		{used: []string{"--poll-interval", "--until-nxdomain"}, expectFlag: "--until-nxdomain"},
+		{used: []string{"--compare", "--servers-parallel"}, expectErr: "--compare conflicts with --servers-parallel"},
+		{used: []string{"--until-noerror", "--until-nxdomain"}, expectErr: "--until-noerror conflicts with --until-nxdomain"},
+		{used: []string{"--compare", "--fail-on-bogon"}, expectErr: "--fail-on-bogon conflicts with --compare"},
+		{used: []string{"--servers-parallel", "--min-ttl"}, expectErr: "--min-ttl conflicts with --servers-parallel"},
+		{used: []string{"--raw-query", "--count-answers"}, expectErr: "--count-answers conflicts with --raw-query"},
+		{used: []string{"--raw-query", "--output-dir"}, expectErr: "--output-dir conflicts with --raw-query"},
+		{used: []string{"--raw-query", "--summary-json"}, expectErr: "--summary-json conflicts with --raw-query"},
+		{used: []string{"--raw-query", "--tcp-fallback"}, expectErr: "--tcp-fallback conflicts with --raw-query"},
+		{used: []string{"--raw-query", "+short"}, expectErr: "+short conflicts with --raw-query"},
+		{used: []string{"--retry-protocols", "+tls"}, expectErr: "+tls conflicts with --retry-protocols"},
+		{used: []string{"--tcp-keepalive", "--tcp-mss"}, expectErr: "--tcp-mss conflicts with --tcp-keepalive"},
+		{used: []string{"--repeat-max"}, expectErr: "--repeat-max requires --repeat-until-change"},
+		{
+			used:      []string{"--compare", "--poll-timeout"},
+			expectErr: "--poll-timeout requires --until-noerror or --until-nxdomain",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(strings.Join(tc.used, " "), func(t *testing.T) {
+			used := map[string]bool{}
+			for _, option := range tc.used {
+				used[option] = true
+			}
+			mode, err := selectRunMode(used)
+			if tc.expectErr != "" {
+				if err == nil || err.Error() != tc.expectErr {
+					t.Fatalf("expected %q, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode.Flag != tc.expectFlag {
+				t.Fatalf("expected %q, got %q", tc.expectFlag, mode.Flag)
+			}
+		})
+	}
+}
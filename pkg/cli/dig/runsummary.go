@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/common/fsx"
+	"github.com/rbmk-project/dnscore"
+)
+
+// runSummary summarizes all the queries sent during a single
+// dig run and is what we write into the SummaryFile. We also
+// use it to emit the dnsSweepSummary event (see logSweep).
+type runSummary struct {
+	// Queries counts the queries we sent, including retries.
+	Queries int `json:"queries"`
+
+	// Outcomes classifies the outcome of each query.
+	Outcomes sweepSummary `json:"outcomes"`
+
+	// Servers contains the addresses of the servers we queried,
+	// without duplicates, in the order in which we queried them.
+	Servers []string `json:"servers"`
+
+	// QueryBytes is the total size of the queries we sent.
+	QueryBytes int `json:"queryBytes"`
+
+	// ResponseBytes is the total size of the responses we received.
+	ResponseBytes int `json:"responseBytes"`
+
+	// DurationMs is the wall-clock duration of the run in milliseconds.
+	DurationMs int64 `json:"durationMs"`
+
+	// T0 is the time when the run started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the run ended.
+	T time.Time `json:"t"`
+
+	// mu protects the fields above, since we may send
+	// queries concurrently (see queryParallel).
+	mu sync.Mutex
+}
+
+// add accounts for the outcome of sending the given query to the given server.
+func (s *runSummary) add(addr *dnscore.ServerAddr, query, resp *dns.Msg, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Queries++
+	s.Outcomes.add(query, resp, err)
+	if !slices.Contains(s.Servers, addr.Address) {
+		s.Servers = append(s.Servers, addr.Address)
+	}
+	s.QueryBytes += query.Len()
+	if resp != nil {
+		s.ResponseBytes += resp.Len()
+	}
+}
+
+// wrap returns a [dnsTransport] that accounts for each query
+// sent using the given [dnsTransport] in the summary.
+func (s *runSummary) wrap(txp dnsTransport) dnsTransport {
+	return &summarizingTransport{txp: txp, summary: s}
+}
+
+// logSweep emits the outcomes as a dnsSweepSummary structured log
+// event using t as the time when the run ended.
+func (s *runSummary) logSweep(ctx context.Context, logger *slog.Logger, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Outcomes.log(ctx, logger, s.Queries, s.T0, t)
+}
+
+// writeFile finalizes the summary using t as the time when the
+// run ended and writes the summary as JSON to the given path.
+func (s *runSummary) writeFile(fsys fsx.FS, path string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.T = t
+	s.DurationMs = t.Sub(s.T0).Milliseconds()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	filep, err := fsys.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := filep.Write(append(data, '\n')); err != nil {
+		filep.Close()
+		return err
+	}
+	return filep.Close()
+}
+
+// summarizingTransport is a [dnsTransport] updating a [*runSummary].
+type summarizingTransport struct {
+	txp     dnsTransport
+	summary *runSummary
+}
+
+var _ dnsTransport = &summarizingTransport{}
+
+// Query implements [dnsTransport].
+func (st *summarizingTransport) Query(
+	ctx context.Context, addr *dnscore.ServerAddr, query *dns.Msg) (*dns.Msg, error) {
+	resp, err := st.txp.Query(ctx, addr, query)
+	st.summary.add(addr, query, resp, err)
+	return resp, err
+}
+
+// QueryWithDuplicates implements [dnsTransport].
+//
+// We account for each response, including duplicates.
+func (st *summarizingTransport) QueryWithDuplicates(
+	ctx context.Context, addr *dnscore.ServerAddr, query *dns.Msg) <-chan *dnscore.MessageOrError {
+	inputs := st.txp.QueryWithDuplicates(ctx, addr, query)
+	outputs := make(chan *dnscore.MessageOrError)
+	go func() {
+		defer close(outputs)
+		for entry := range inputs {
+			st.summary.add(addr, query, entry.Msg, entry.Err)
+			outputs <- entry
+		}
+	}()
+	return outputs
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/common/fsx"
+	"github.com/rbmk-project/dnscore"
+)
+
+func TestRunSummary(t *testing.T) {
+	txp := &mockTransport{
+		responses: map[string][]dns.RR{
+			"8.8.8.8:53": {newTestA("93.184.216.34")},
+			"9.9.9.9:53": nil,
+		},
+		rcodes: map[string]int{
+			"9.9.9.9:53": dns.RcodeNameError,
+		},
+	}
+	t0 := time.Now()
+	summary := &runSummary{T0: t0}
+	wrapped := summary.wrap(txp)
+
+	// Send a query to each server, including one that fails, and
+	// send the second query twice to check for duplicate servers.
+	for _, server := range []string{"8.8.8.8:53", "9.9.9.9:53", "9.9.9.9:53", "1.1.1.1:53"} {
+		addr := dnscore.NewServerAddr(dnscore.ProtocolUDP, server)
+		wrapped.Query(context.Background(), addr, newTestQuery(t))
+	}
+	addr := dnscore.NewServerAddr(dnscore.ProtocolUDP, "8.8.8.8:53")
+	for range wrapped.QueryWithDuplicates(context.Background(), addr, newTestQuery(t)) {
+		// nothing
+	}
+
+	path := filepath.Join(t.TempDir(), "summary.json")
+	if err := summary.writeFile(fsx.OsFS{}, path, t0.Add(1500*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got runSummary
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Queries != 5 {
+		t.Fatalf("expected 5 queries, got %d", got.Queries)
+	}
+	expectOutcomes := sweepSummary{Successes: 2, NXDomains: 2, Failures: 1}
+	if got.Outcomes != expectOutcomes {
+		t.Fatalf("expected %+v, got %+v", expectOutcomes, got.Outcomes)
+	}
+	expectServers := []string{"8.8.8.8:53", "9.9.9.9:53", "1.1.1.1:53"}
+	if len(got.Servers) != len(expectServers) {
+		t.Fatalf("expected %v, got %v", expectServers, got.Servers)
+	}
+	for idx := range expectServers {
+		if got.Servers[idx] != expectServers[idx] {
+			t.Fatalf("expected %v, got %v", expectServers, got.Servers)
+		}
+	}
+	if got.QueryBytes != 5*newTestQuery(t).Len() {
+		t.Fatalf("unexpected query bytes: %d", got.QueryBytes)
+	}
+	if got.ResponseBytes <= 0 {
+		t.Fatalf("unexpected response bytes: %d", got.ResponseBytes)
+	}
+	if got.DurationMs != 1500 {
+		t.Fatalf("expected 1500 ms, got %d", got.DurationMs)
+	}
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// sweepSummary accumulates the outcomes of the queries
+// sent during a single dig run (see runSummary).
+type sweepSummary struct {
+	// Bogons counts the responses containing bogon addresses.
+	Bogons int `json:"bogons"`
+
+	// Failures counts the queries that failed for reasons
+	// other than a timeout (e.g., connection refused).
+	Failures int `json:"failures"`
+
+	// NoData counts the responses without valid answers.
+	NoData int `json:"noData"`
+
+	// NXDomains counts the NXDOMAIN responses.
+	NXDomains int `json:"nxdomains"`
+
+	// Successes counts the responses containing valid answers.
+	Successes int `json:"successes"`
+
+	// Timeouts counts the queries that timed out.
+	Timeouts int `json:"timeouts"`
+}
+
+// add accounts for the outcome of sending the given query.
+//
+// Note that we count bogons in addition to successes, since
+// a response containing bogons is otherwise a valid response.
+func (s *sweepSummary) add(query, resp *dns.Msg, err error) {
+	switch {
+	case err != nil && isTimeout(err):
+		s.Timeouts++
+
+	case err != nil:
+		s.Failures++
+
+	case resp.Rcode == dns.RcodeNameError:
+		s.NXDomains++
+
+	case resp.Rcode != dns.RcodeSuccess:
+		s.Failures++
+
+	case len(query.Question) <= 0:
+		s.Failures++
+
+	default:
+		if _, err := dnscore.ValidAnswers(query.Question[0], resp); err != nil {
+			s.NoData++
+			return
+		}
+		s.Successes++
+		if _, found := findBogon(resp); found {
+			s.Bogons++
+		}
+	}
+}
+
+// log emits the summary as a structured log event, using t0
+// and t to indicate the time interval spanned by the sweep
+// and queries as the number of queries sent.
+func (s *sweepSummary) log(ctx context.Context, logger *slog.Logger, queries int, t0, t time.Time) {
+	logger.InfoContext(
+		ctx,
+		"dnsSweepSummary",
+		slog.Int("bogons", s.Bogons),
+		slog.Int("failures", s.Failures),
+		slog.Int("noData", s.NoData),
+		slog.Int("nxdomains", s.NXDomains),
+		slog.Int("queries", queries),
+		slog.Int("successes", s.Successes),
+		slog.Int("timeouts", s.Timeouts),
+		slog.Duration("totalDuration", t.Sub(t0)),
+		slog.Time("t0", t0),
+		slog.Time("t", t),
+	)
+}
+
+// isSweep returns whether the task uses a run mode sending several
+// queries (e.g., --compare), for which we emit a dnsSweepSummary.
+func (task *Task) isSweep() bool {
+	return len(task.CompareServers) > 0 || len(task.ParallelServers) > 0 ||
+		len(task.RetryProtocols) > 0 || task.PollUntil != "" || task.RepeatInterval > 0
+}
+
+// isTimeout returns whether the given error is a timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
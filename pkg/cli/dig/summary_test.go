@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+func TestSweepSummaryAdd(t *testing.T) {
+	query := newTestQuery(t)
+
+	// newResp returns a response to query with the given RCODE and answers.
+	newResp := func(rcode int, answers ...dns.RR) *dns.Msg {
+		resp := &dns.Msg{}
+		resp.SetReply(query)
+		resp.Rcode = rcode
+		resp.Answer = answers
+		return resp
+	}
+
+	var summary sweepSummary
+	summary.add(query, newResp(dns.RcodeSuccess, newTestA("93.184.216.34")), nil)
+	summary.add(query, newResp(dns.RcodeSuccess, newTestA("10.10.34.34")), nil)
+	summary.add(query, newResp(dns.RcodeSuccess), nil)
+	summary.add(query, newResp(dns.RcodeNameError), nil)
+	summary.add(query, newResp(dns.RcodeServerFailure), nil)
+	summary.add(query, nil, fmt.Errorf("read: %w", os.ErrDeadlineExceeded))
+	summary.add(query, nil, context.DeadlineExceeded)
+	summary.add(query, nil, errors.New("connection refused"))
+
+	expect := sweepSummary{
+		Bogons:    1,
+		Failures:  2,
+		NoData:    1,
+		NXDomains: 1,
+		Successes: 2,
+		Timeouts:  2,
+	}
+	if summary != expect {
+		t.Fatalf("expected %+v, got %+v", expect, summary)
+	}
+}
+
+func TestRunSummaryLogSweep(t *testing.T) {
+	// parseSweep parses the last record, which must be a dnsSweepSummary.
+	type sweepRecord struct {
+		Msg           string
+		Bogons        int
+		Failures      int
+		NoData        int
+		NXDomains     int
+		Queries       int
+		Successes     int
+		Timeouts      int
+		TotalDuration time.Duration
+	}
+	parseSweep := func(t *testing.T, logs string) sweepRecord {
+		lines := strings.Split(strings.TrimSpace(logs), "\n")
+		var record sweepRecord
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Msg != "dnsSweepSummary" {
+			t.Fatalf("unexpected last record: %s", lines[len(lines)-1])
+		}
+		return record
+	}
+
+	t.Run("with --compare", func(t *testing.T) {
+		txp := &mockTransport{
+			responses: map[string][]dns.RR{
+				"8.8.8.8:53":  {newTestA("93.184.216.34")},
+				"10.0.0.1:53": {newTestA("10.10.34.34")},
+				"1.1.1.1:53":  {},
+			},
+			rcodes: map[string]int{
+				"1.1.1.1:53": dns.RcodeNameError,
+			},
+		}
+
+		var logs strings.Builder
+		summary := &runSummary{T0: time.Now()}
+		task := newTestTask()
+		task.CompareServers = []string{"8.8.8.8", "10.0.0.1", "1.1.1.1", "10.0.0.2"}
+		err := task.compare(context.Background(), summary.wrap(txp), dnscore.ProtocolUDP, newTestQuery(t))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		summary.logSweep(context.Background(), newTestLogger(&logs), summary.T0.Add(time.Second))
+
+		record := parseSweep(t, logs.String())
+		if record.Queries != 4 || record.Successes != 2 || record.Bogons != 1 || record.NXDomains != 1 ||
+			record.Failures != 1 || record.NoData != 0 || record.Timeouts != 0 ||
+			record.TotalDuration != time.Second {
+			t.Fatalf("unexpected summary: %+v", record)
+		}
+	})
+
+	t.Run("with --repeat-until-change", func(t *testing.T) {
+		txp := &changingTransport{
+			mockTransport: mockTransport{
+				responses: map[string][]dns.RR{
+					"8.8.8.8:53": {newTestA("93.184.216.34")},
+				},
+			},
+			changeAt: 100,
+			failAt:   []int{2},
+		}
+
+		var logs strings.Builder
+		summary := &runSummary{T0: time.Now()}
+		task := newTestTask()
+		task.RepeatInterval = time.Millisecond
+		task.RepeatMax = 3
+		logger := newTestLogger(&logs)
+		if _, err := task.repeatUntilChange(context.Background(), logger,
+			summary.wrap(txp), dnscore.ProtocolUDP, newTestQuery(t)); err == nil {
+			t.Fatal("expected an error")
+		}
+		summary.logSweep(context.Background(), logger, summary.T0.Add(time.Second))
+
+		record := parseSweep(t, logs.String())
+		if record.Queries != 3 || record.Successes != 2 || record.Failures != 1 ||
+			record.TotalDuration != time.Second {
+			t.Fatalf("unexpected summary: %+v", record)
+		}
+	})
+}
+
+func TestTaskIsSweep(t *testing.T) {
+	cases := []struct {
+		name   string
+		edit   func(task *Task)
+		expect bool
+	}{
+		{name: "single query", edit: func(task *Task) {}, expect: false},
+		{name: "compare", edit: func(task *Task) { task.CompareServers = []string{"8.8.8.8"} }, expect: true},
+		{name: "parallel", edit: func(task *Task) { task.ParallelServers = []string{"8.8.8.8"} }, expect: true},
+		{name: "poll", edit: func(task *Task) { task.PollUntil = "noerror" }, expect: true},
+		{name: "repeat", edit: func(task *Task) { task.RepeatInterval = time.Second }, expect: true},
+		{name: "retry", edit: func(task *Task) { task.RetryProtocols = []string{"udp"} }, expect: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := newTestTask()
+			tc.edit(task)
+			if got := task.isSweep(); got != tc.expect {
+				t.Fatalf("expected %v, got %v", tc.expect, got)
+			}
+		})
+	}
+}
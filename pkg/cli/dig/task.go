@@ -4,12 +4,14 @@ package dig
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"strings"
 	"sync"
@@ -17,6 +19,7 @@ import (
 
 	"github.com/miekg/dns"
 	"github.com/rbmk-project/common/closepool"
+	"github.com/rbmk-project/common/fsx"
 	"github.com/rbmk-project/dnscore"
 	"github.com/rbmk-project/rbmk/internal/testable"
 	"github.com/rbmk-project/x/netcore"
@@ -27,19 +30,174 @@ import (
 // The zero value is not ready to use. Please, make sure
 // to initialize all the fields marked as MANDATORY.
 type Task struct {
+	// ALPN is the OPTIONAL list of ALPN protocols to offer during the
+	// TLS handshake when using DoT or DoH. When empty, we use the
+	// default ALPN list selected depending on the server port.
+	ALPN []string
+
+	// BindInterface is the OPTIONAL name of the network interface to
+	// which we bind the outgoing sockets. This feature is only supported
+	// on Linux, where it requires the SO_BINDTODEVICE socket option.
+	BindInterface string
+
+	// BootstrapServer is the OPTIONAL address of the server to query
+	// over UDP for resolving the name of the server to use, rather than
+	// using the system resolver. This is useful when using DoH with a
+	// server specified using its domain name (e.g., dns.google).
+	BootstrapServer string
+
+	// CampaignID is the OPTIONAL identifier of the measurement campaign
+	// this run belongs to. When set, each structured log record includes
+	// the campaign ID and the time when the run started.
+	CampaignID string
+
+	// CertDumpDir is the OPTIONAL directory where to write the
+	// peer certificates observed when using DoT or DoH as PEM files.
+	// When this field is set, the FS field becomes MANDATORY.
+	CertDumpDir string
+
+	// CheckingDisabled is the OPTIONAL flag indicating whether we should
+	// set the CD (checking disabled) bit in the query, which is useful to
+	// fetch data that a validating resolver would otherwise hide.
+	CheckingDisabled bool
+
+	// ClientSubnet is the OPTIONAL prefix to send using the
+	// edns-client-subnet option (RFC 7871). When set, we also print
+	// and log the scope prefix length returned by the server.
+	ClientSubnet netip.Prefix
+
+	// CompareServers is the OPTIONAL list of servers to query using
+	// the same question to compare their responses. When this list is
+	// not empty, we ignore ServerAddr and query each server in order.
+	CompareServers []string
+
+	// CountAnswers is the OPTIONAL range of the number of answers of the
+	// queried type. When nil, we do not check it (see checkCountAnswers).
+	CountAnswers *answerCountRange
+
+	// Deadline is the OPTIONAL absolute time by which the whole
+	// operation must complete. When this field is the zero value,
+	// we only bound the operation using the default timeout.
+	Deadline time.Time
+
+	// DiffWriter is the MANDATORY [io.Writer] where we should write
+	// the differences between responses when comparing servers.
+	DiffWriter io.Writer
+
+	// DuplicatesTimeout is the OPTIONAL maximum amount of time during
+	// which we collect duplicate responses when WaitDuplicates is set.
+	// When zero, we collect duplicates until the overall timeout expires.
+	DuplicatesTimeout time.Duration
+
+	// EDNSBufferSize is the OPTIONAL EDNS0 UDP payload size to advertise
+	// regardless of the protocol. When zero, we advertise a size that
+	// depends on the protocol (i.e., smaller when using UDP).
+	EDNSBufferSize uint16
+
+	// EDNSFlags is the OPTIONAL bitmask of EDNS0 header flags to set
+	// in the OPT record, in addition to the DO flag we automatically
+	// set when using DoT and DoH (see RFC 6891 for the bit layout).
+	EDNSFlags uint16
+
+	// Expect is the OPTIONAL list of addresses that the A and AAAA
+	// answers must contain. When the response does not contain all of
+	// them, Run returns an error wrapping errExpectationFailed.
+	Expect []netip.Addr
+
+	// FailOnBogon is the OPTIONAL flag indicating whether we should
+	// fail when the response contains bogon A or AAAA answers.
+	FailOnBogon bool
+
+	// FailOnEmpty is the OPTIONAL flag indicating whether we should
+	// fail when the response contains no valid answers (NODATA).
+	FailOnEmpty bool
+
+	// FS is the OPTIONAL file system to use for writing files.
+	FS fsx.FS
+
+	// HexDumpWriter is the MANDATORY [io.Writer] where we should
+	// write the hex dump of the raw query and response bytes.
+	HexDumpWriter io.Writer
+
+	// Insecure is the OPTIONAL flag indicating whether we should skip
+	// verifying the server certificate when using DoT or DoH.
+	Insecure bool
+
+	// KeepaliveIdle is the OPTIONAL amount of time during which we keep
+	// the TCP or DoT connection idle after receiving the response, to check
+	// whether the server closes it. When zero, we do not probe.
+	KeepaliveIdle time.Duration
+
 	// LogsWriter is the MANDATORY [io.Writer] where
 	// we should write structured logs.
 	LogsWriter io.Writer
 
+	// MinTTL is the OPTIONAL minimum TTL, in seconds, of the answers. When
+	// nonzero, we fail if any answer has a lower TTL (see checkMinTTL).
+	MinTTL uint32
+
 	// Name is the MANDATORY name to query.
 	Name string
 
+	// NoRecursion is the OPTIONAL flag indicating whether we should
+	// clear the RD (recursion desired) bit in the query, which is useful
+	// to query authoritative servers and observe referrals.
+	NoRecursion bool
+
+	// NoValidate is the OPTIONAL flag indicating whether we should skip
+	// validating the response and mapping its RCODE to an error, such
+	// that we only fail when the query round trip itself fails.
+	NoValidate bool
+
+	// OutputDir is the OPTIONAL directory where to write the result of
+	// each query as OutputDir/<name>/<type>/<server>.json. When this
+	// field is set, the FS field becomes MANDATORY.
+	OutputDir string
+
+	// OutputFormat is the OPTIONAL format of the responses we write to the
+	// ShortWriter: "answers" (the answer RRs in presentation format), "csv"
+	// (CSV rows after a header row written once by Run), "json" (one JSON
+	// object per line), "short" (like +short), or "short=ip" (like +short=ip).
+	// When empty or "dig", we only write the human readable response to the
+	// ResponseWriter (see setOutputFormat, which configures the writers).
+	OutputFormat string
+
+	// ParallelServers contains the OPTIONAL servers to query concurrently,
+	// merging the answers of their responses (see queryParallel).
+	ParallelServers []string
+
+	// PhasesWriter is the OPTIONAL [io.Writer] where we write the timing
+	// of the connect, TLS handshake, and query phases after running.
+	PhasesWriter io.Writer
+
+	// PollInterval is the interval between queries when polling
+	// (see PollUntil). MANDATORY if PollUntil is set.
+	PollInterval time.Duration
+
+	// PollTimeout is the maximum amount of time during which we
+	// poll (see PollUntil). MANDATORY if PollUntil is set.
+	PollTimeout time.Duration
+
+	// PollUntil is the OPTIONAL condition to wait for by polling the
+	// name: "noerror" waits until the name resolves to valid answers and
+	// "nxdomain" waits until the name does not exist (see pollUntil).
+	PollUntil string
+
 	// Protocol is the MANDATORY protocol to use,
-	// expressed as a string. For example, "udp" or "tcp".
+	// expressed as a string. For example, "udp" or "tcp". We also
+	// accept aliases such as "tls" and "https" (see parseProtocol).
 	//
 	// See [dnscore.NewServerAddr] for more details.
 	Protocol string
 
+	// QueryID is the OPTIONAL query ID to use. When nil, we
+	// use a random query ID, which is the default behavior.
+	QueryID *uint16
+
+	// QueryIDWriter is the MANDATORY [io.Writer] where we should
+	// write the ID of each query we send.
+	QueryIDWriter io.Writer
+
 	// QueryType is the MANDATORY query type expressed
 	// as a string. For example, "A" or "AAAA".
 	QueryType string
@@ -48,18 +206,40 @@ type Task struct {
 	// write the query before sending it.
 	QueryWriter io.Writer
 
+	// RawQuery contains the OPTIONAL raw bytes to send as the query
+	// rather than building a query using Name and QueryType. When set,
+	// we write a hex dump of the raw response to the ResponseWriter
+	// without parsing or validating it.
+	RawQuery []byte
+
+	// RepeatInterval is the OPTIONAL interval between repeated queries.
+	// When nonzero, we repeat the query until the response differs from
+	// the first response, or we have sent RepeatMax queries.
+	RepeatInterval time.Duration
+
+	// RepeatMax is the maximum number of queries to send when
+	// RepeatInterval is nonzero. MANDATORY if RepeatInterval is set.
+	RepeatMax int
+
 	// ResponseWriter is the MANDATORY [io.Writer] where we should
 	// write the full response when we received it.
 	ResponseWriter io.Writer
 
-	// ShortIP is a flag that ensures that `+short=ip` only
-	// prints the IP addresses in the response.
-	ShortIP bool
+	// RetryProtocols is the OPTIONAL list of protocols (e.g., "udp",
+	// "tcp", "dot") to try in order until one of them yields a valid
+	// response. When this list is not empty, we ignore the Protocol and
+	// ServerPort fields and use the default port of each protocol.
+	RetryProtocols []string
 
-	// ShortWriter is the MANDATORY [io.Writer] where we should
-	// write the short response when we received it.
+	// ShortWriter is the MANDATORY [io.Writer] where we should write
+	// the response formatted according to the OutputFormat.
 	ShortWriter io.Writer
 
+	// RootCAs is the OPTIONAL [*x509.CertPool] to use for verifying
+	// the server certificate when using DoT or DoH. If nil, we use
+	// the default root CAs (i.e., the system's root CAs).
+	RootCAs *x509.CertPool
+
 	// ServerAddr is the MANDATORY address of the server
 	// to query, for example "8.8.8.8", "1.1.1.1".
 	ServerAddr string
@@ -68,6 +248,32 @@ type Task struct {
 	// query. For example, "53".
 	ServerPort string
 
+	// Servers is the OPTIONAL list of servers to query in order
+	// until one of them responds. When this list is not empty, we
+	// ignore ServerAddr (e.g., when using the servers listed
+	// inside the system's resolv.conf file).
+	Servers []string
+
+	// SummaryFile is the OPTIONAL path of the file where to write a JSON
+	// object summarizing all the queries sent during the run. When this
+	// field is set, the FS field becomes MANDATORY.
+	SummaryFile string
+
+	// TCPFallback is the OPTIONAL flag indicating whether we should
+	// retry using TCP when the response received over UDP is
+	// truncated (i.e., it has the TC bit set).
+	TCPFallback bool
+
+	// TCPKeepalive is the OPTIONAL flag indicating whether we should
+	// include the edns-tcp-keepalive option (RFC 7828) in queries sent
+	// using TCP or DoT, and print the timeout advertised by the server.
+	TCPKeepalive bool
+
+	// TCPMSS is the OPTIONAL maximum segment size to set for TCP
+	// connections before connecting (Linux only). When set, we also
+	// print and log whether the response completed (see reportTCPMSS).
+	TCPMSS int
+
 	// URLPath is the MANDATORY URL path when using DoH.
 	URLPath string
 
@@ -77,35 +283,49 @@ type Task struct {
 	WaitDuplicates bool
 }
 
+// queryTypeInfo contains information about a supported query type.
+type queryTypeInfo struct {
+	// Type is the DNS query type.
+	Type uint16
+
+	// Description describes what the query type resolves.
+	Description string
+}
+
 // queryTypeMap maps query types strings to DNS query types.
-var queryTypeMap = map[string]uint16{
-	"A":     dns.TypeA,
-	"AAAA":  dns.TypeAAAA,
-	"CNAME": dns.TypeCNAME,
-	"HTTPS": dns.TypeHTTPS,
-	"MX":    dns.TypeMX,
-	"NS":    dns.TypeNS,
+var queryTypeMap = map[string]queryTypeInfo{
+	"A":     {dns.TypeA, "IPv4 addresses"},
+	"AAAA":  {dns.TypeAAAA, "IPv6 addresses"},
+	"CNAME": {dns.TypeCNAME, "canonical name"},
+	"HINFO": {dns.TypeHINFO, "host CPU and operating system"},
+	"HTTPS": {dns.TypeHTTPS, "HTTPS service binding (ALPNs, IP hints, etc.)"},
+	"LOC":   {dns.TypeLOC, "geographical location"},
+	"MX":    {dns.TypeMX, "mail exchange servers"},
+	"NAPTR": {dns.TypeNAPTR, "naming authority pointers (ENUM, SIP, etc.)"},
+	"NS":    {dns.TypeNS, "name servers"},
+	"SVCB":  {dns.TypeSVCB, "generic service binding (priority, target, params)"},
 }
 
-// protocolMap maps protocol strings to DNS protocols.
-var protocolMap = map[string]dnscore.Protocol{
-	"udp": dnscore.ProtocolUDP,
-	"tcp": dnscore.ProtocolTCP,
-	"dot": dnscore.ProtocolDoT,
-	"doh": dnscore.ProtocolDoH,
+// dnsTransport abstracts the [*dnscore.Transport] methods we use.
+type dnsTransport interface {
+	Query(ctx context.Context, addr *dnscore.ServerAddr, query *dns.Msg) (*dns.Msg, error)
+	QueryWithDuplicates(ctx context.Context, addr *dnscore.ServerAddr, query *dns.Msg) <-chan *dnscore.MessageOrError
 }
 
-// newServerAddr returns a new server address string based on the protocol
-// and the specific fields configured for the task.
-func (task *Task) newServerAddr(protocol dnscore.Protocol) string {
+// Ensure that [*dnscore.Transport] implements [dnsTransport].
+var _ dnsTransport = &dnscore.Transport{}
+
+// newServerAddr returns a new server address string based on the protocol,
+// the given server address, and the specific fields configured for the task.
+func (task *Task) newServerAddr(protocol dnscore.Protocol, address string) string {
 	switch protocol {
 	case dnscore.ProtocolUDP, dnscore.ProtocolTCP, dnscore.ProtocolDoT:
-		return net.JoinHostPort(task.ServerAddr, task.ServerPort)
+		return net.JoinHostPort(address, task.ServerPort)
 
 	case dnscore.ProtocolDoH:
 		URL := &url.URL{
 			Scheme: "https",
-			Host:   net.JoinHostPort(task.ServerAddr, task.ServerPort),
+			Host:   net.JoinHostPort(address, task.ServerPort),
 			Path:   task.URLPath,
 		}
 		return URL.String()
@@ -116,14 +336,35 @@ func (task *Task) newServerAddr(protocol dnscore.Protocol) string {
 }
 
 // Run runs the task and returns an error.
-func (task *Task) Run(ctx context.Context) error {
-	// Setup the overal operation timeout using the context
-	const timeout = 5 * time.Second
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+func (task *Task) Run(ctx context.Context) (err error) {
+	// Setup the overal operation timeout using the context, which
+	// includes the idle time when probing the keepalive behavior, the
+	// time spent waiting between repeated queries, and the poll timeout
+	timeout := 5*time.Second + task.KeepaliveIdle
+	if task.RepeatInterval > 0 {
+		timeout += time.Duration(task.RepeatMax) * task.RepeatInterval
+	}
+	if task.PollUntil != "" {
+		timeout += task.PollTimeout
+	}
+	ctx, cancel := context.WithDeadline(ctx, task.deadline(time.Now(), timeout))
 	defer cancel()
 
 	// Set up the JSON logger for writing the measurements
-	logger := slog.New(slog.NewJSONHandler(task.LogsWriter, &slog.HandlerOptions{}))
+	logger := task.newLogger(time.Now())
+
+	// Trace the timing of the phases of the query, if requested
+	if task.PhasesWriter != nil {
+		tracer := &phaseTracer{}
+		logger = slog.New(tracer.wrap(logger.Handler()))
+		defer tracer.write(task.PhasesWriter)
+	}
+
+	// Dump the raw query and response bytes, if requested
+	if task.HexDumpWriter != io.Discard {
+		dumper := &hexDumper{w: task.HexDumpWriter}
+		logger = slog.New(dumper.wrap(logger.Handler()))
+	}
 
 	// Create a pool containing closers
 	pool := &closepool.Pool{}
@@ -132,8 +373,20 @@ func (task *Task) Run(ctx context.Context) error {
 	// Create netcore network instance
 	netx := &netcore.Network{}
 	netx.RootCAs = testable.RootCAs.Get()
-	netx.DialContextFunc = testable.DialContext.Get()
+	if task.RootCAs != nil {
+		netx.RootCAs = task.RootCAs
+	}
+	control, err := task.dialerControl()
+	if err != nil {
+		return err
+	}
+	netx.DialContextFunc = testable.DialContext.GetWithControl(control)
 	netx.Logger = logger
+	netx.NewTLSClientConn = task.newTLSClientConn
+	if task.CertDumpDir != "" {
+		dumper := &certDumper{Dir: task.CertDumpDir, FS: task.FS, Logger: logger}
+		netx.NewTLSClientConn = dumper.wrap(netx.NewTLSClientConn)
+	}
 	netx.WrapConn = func(ctx context.Context, netx *netcore.Network, conn net.Conn) net.Conn {
 		conn = netcore.WrapConn(ctx, netx, conn)
 		pool.Add(conn)
@@ -154,83 +407,257 @@ func (task *Task) Run(ctx context.Context) error {
 	}
 	transport.Logger = logger
 
+	// Resolve the server name using the bootstrap server, if requested
+	if task.BootstrapServer != "" {
+		netx.LookupHostFunc = task.newBootstrapLookupHost(transport)
+	}
+
+	// Summarize all the queries we send, if requested, and emit a final
+	// dnsSweepSummary event when the run mode sends several queries
+	var txp dnsTransport = transport
+	summary := &runSummary{T0: time.Now()}
+	if task.SummaryFile != "" || task.isSweep() {
+		txp = summary.wrap(txp)
+	}
+	if task.isSweep() {
+		defer func() {
+			summary.logSweep(ctx, logger, time.Now())
+		}()
+	}
+	if task.SummaryFile != "" {
+		defer func() {
+			if werr := summary.writeFile(task.FS, task.SummaryFile, time.Now()); werr != nil && err == nil {
+				err = fmt.Errorf("cannot write summary: %w", werr)
+			}
+		}()
+	}
+
 	// Determine the DNS query type
-	queryType, ok := queryTypeMap[task.QueryType]
+	qtinfo, ok := queryTypeMap[task.QueryType]
 	if !ok {
 		return fmt.Errorf("unsupported query type: %s", task.QueryType)
 	}
 
 	// Determine the server protocol
-	protocol, ok := protocolMap[task.Protocol]
-	if !ok {
-		return fmt.Errorf("unsupported protocol: %s", task.Protocol)
+	protocol, err := parseProtocol(task.Protocol)
+	if err != nil {
+		return err
 	}
 
-	// Create the server address
-	server := dnscore.NewServerAddr(protocol, task.newServerAddr(protocol))
-	flags := 0
-	maxlength := uint16(dnscore.EDNS0SuggestedMaxResponseSizeUDP)
-	if protocol == dnscore.ProtocolDoT || protocol == dnscore.ProtocolDoH {
-		flags |= dnscore.EDNS0FlagDO | dnscore.EDNS0FlagBlockLengthPadding
+	// Write the CSV header once, if requested
+	if task.OutputFormat == outputFormatCSV {
+		fmt.Fprintf(task.ShortWriter, "%s", formatCSV(csvHeader))
 	}
-	if protocol != dnscore.ProtocolUDP {
-		maxlength = dnscore.EDNS0SuggestedMaxResponseSizeOtherwise
+
+	// Send the raw query bytes, if requested
+	if len(task.RawQuery) > 0 {
+		_, err := task.rawExchange(ctx, logger, netx, transport.HTTPClient, protocol)
+		if err != nil {
+			return fmt.Errorf("raw query round-trip failed: %w", err)
+		}
+		return nil
+	}
+
+	// Try each of the RetryProtocols in order, if requested
+	if len(task.RetryProtocols) > 0 {
+		query, response, err := task.retryProtocols(ctx, logger, txp, qtinfo.Type)
+		if err != nil {
+			err = fmt.Errorf("query round-trip failed: %w", err)
+			return errors.Join(err, task.checkExpect(ctx, logger, nil))
+		}
+		pool.Close()
+		task.logClientSubnetScope(ctx, logger, response)
+		if err := task.checkExpect(ctx, logger, response); err != nil {
+			return err
+		}
+		return task.checkResponse(ctx, logger, query, response)
 	}
 
 	// Create the DNS query
-	optEDNS0 := dnscore.QueryOptionEDNS0(maxlength, flags)
-	query, err := dnscore.NewQuery(task.Name, queryType, optEDNS0)
+	query, err := task.newQuery(protocol, qtinfo.Type)
 	if err != nil {
 		return fmt.Errorf("cannot create query: %w", err)
 	}
-	fmt.Fprintf(task.QueryWriter, ";; Query:\n%s\n", query.String())
+	task.writeQuery(ctx, logger, query)
+
+	// Probe how the server handles idle connections, if requested
+	if task.KeepaliveIdle > 0 {
+		dial := netx.DialContext
+		if protocol == dnscore.ProtocolDoT {
+			dial = netx.DialTLSContext
+		}
+		response, err := task.probeKeepalive(ctx, logger, dial, protocol, query)
+		if err != nil {
+			err = fmt.Errorf("query round-trip failed: %w", err)
+			return errors.Join(err, task.checkExpect(ctx, logger, nil))
+		}
+		pool.Close()
+		task.logClientSubnetScope(ctx, logger, response)
+		if err := task.checkExpect(ctx, logger, response); err != nil {
+			return err
+		}
+		return task.checkResponse(ctx, logger, query, response)
+	}
+
+	// Compare the responses of several servers, if requested
+	if len(task.CompareServers) > 0 {
+		return task.compare(ctx, txp, protocol, query)
+	}
+
+	// Query several servers concurrently and merge the answers, if requested
+	if len(task.ParallelServers) > 0 {
+		return task.queryParallel(ctx, logger, txp, protocol, query)
+	}
+
+	// Poll until the name resolves or stops resolving, if requested
+	if task.PollUntil != "" {
+		return task.pollUntil(ctx, logger, txp, protocol, query)
+	}
 
-	// Perform the DNS query
-	response, err := task.query(ctx, transport, server, query)
+	// Perform the DNS query, repeating it until the response changes if requested
+	var response *dns.Msg
+	if task.RepeatInterval > 0 {
+		response, err = task.repeatUntilChange(ctx, logger, txp, protocol, query)
+	} else {
+		response, err = task.exchangeInOrder(ctx, txp, protocol, query)
+	}
+	task.reportTCPMSS(ctx, logger, response, err)
 	if err != nil {
-		return fmt.Errorf("query round-trip failed: %w", err)
+		err = fmt.Errorf("query round-trip failed: %w", err)
+		return errors.Join(err, task.checkExpect(ctx, logger, nil))
 	}
 
 	// Explicitly close the connections in the pool
 	pool.Close()
 
+	// Log the client subnet scope returned by the server, if any
+	task.logClientSubnetScope(ctx, logger, response)
+
+	// Enforce the expectations, which take precedence over other checks
+	if err := task.checkExpect(ctx, logger, response); err != nil {
+		return err
+	}
+	return task.checkResponse(ctx, logger, query, response)
+}
+
+// checkResponse validates the response to the given query, maps its RCODE
+// to an error, and enforces the policies turning valid responses into failures.
+//
+// When NoValidate is set, we skip validating the response and mapping its
+// RCODE, but we still enforce the policies explicitly requested.
+func (task *Task) checkResponse(ctx context.Context, logger *slog.Logger, query, response *dns.Msg) error {
 	// TODO(bassosimone): we should probably not print the resulting IP addresses
 	// or entries if the response is invalid or the Rcode indicates failure.
 
-	// Validate the DNS response
-	if err = dnscore.ValidateResponse(query, response); err != nil {
-		return fmt.Errorf("cannot validate response: %w", err)
+	if !task.NoValidate {
+		// Validate the DNS response
+		if err := dnscore.ValidateResponse(query, response); err != nil {
+			return fmt.Errorf("cannot validate response: %w", err)
+		}
+
+		// Map the RCODE to an error, if any
+		if err := dnscore.RCodeToError(response); err != nil {
+			return fmt.Errorf("response code indicates error: %w", err)
+		}
 	}
 
-	// Map the RCODE to an error, if any
-	if err := dnscore.RCodeToError(response); err != nil {
-		return fmt.Errorf("response code indicates error: %w", err)
+	// Enforce the policies that turn valid responses into failures
+	if err := task.checkPolicy(query, response); err != nil {
+		return fmt.Errorf("%w: %w", errPolicyViolation, err)
+	}
+	if err := task.checkMinTTL(ctx, logger, response); err != nil {
+		return fmt.Errorf("%w: %w", errPolicyViolation, err)
+	}
+	if err := task.checkCountAnswers(ctx, logger, query, response); err != nil {
+		return fmt.Errorf("%w: %w", errPolicyViolation, err)
 	}
 	return nil
 }
 
+// exchange sends the query to the server at the given address using the
+// given protocol and returns the response or an error.
+//
+// When using UDP, if the response is truncated, we retry using TCP if
+// TCPFallback is set. We do not retry when WaitDuplicates is set because,
+// in such a case, we have already waited for the whole timeout.
+//
+// When OutputDir is set, we also write the result into the OutputDir.
+func (task *Task) exchange(
+	ctx context.Context,
+	txp dnsTransport,
+	protocol dnscore.Protocol,
+	address string,
+	query *dns.Msg,
+) (*dns.Msg, error) {
+	server := dnscore.NewServerAddr(protocol, task.newServerAddr(protocol, address))
+	resp, err := task.query(ctx, txp, server, query)
+	if err == nil && resp.Truncated && protocol == dnscore.ProtocolUDP &&
+		task.TCPFallback && !task.WaitDuplicates {
+		fmt.Fprintf(task.ResponseWriter, ";; Truncated, retrying in TCP mode.\n")
+		server = dnscore.NewServerAddr(dnscore.ProtocolTCP, task.newServerAddr(dnscore.ProtocolTCP, address))
+		resp, err = task.query(ctx, txp, server, query)
+	}
+	if task.OutputDir != "" {
+		if werr := task.writeOutput(address, server, resp, err); werr != nil {
+			return nil, fmt.Errorf("cannot write output: %w", werr)
+		}
+	}
+	return resp, err
+}
+
+// exchangeInOrder sends the query to each of the Servers in order
+// until one of them responds, or to the ServerAddr if Servers is empty.
+func (task *Task) exchangeInOrder(
+	ctx context.Context,
+	txp dnsTransport,
+	protocol dnscore.Protocol,
+	query *dns.Msg,
+) (*dns.Msg, error) {
+	servers := task.Servers
+	if len(servers) <= 0 {
+		servers = []string{task.ServerAddr}
+	}
+	var errv []error
+	for _, address := range servers {
+		resp, err := task.exchange(ctx, txp, protocol, address, query)
+		if err == nil {
+			return resp, nil
+		}
+		errv = append(errv, fmt.Errorf("%s: %w", address, err))
+	}
+	return nil, errors.Join(errv...)
+}
+
 // query performs the query and returns response or error.
 //
 // If the WaitDuplicates flag is set, this function will wait
 // for duplicate responses, emit all the related structured logs,
 // and return the first response received. This function blocks
-// until the timeout configured in the context expires. Note that
+// until the timeout configured in the context or the DuplicatesTimeout,
+// if set, expires, whichever comes first. Note that
 // all responses (including duplicates) are automatically
 // logged through the transport's logger.
 func (task *Task) query(
 	ctx context.Context,
-	txp *dnscore.Transport,
+	txp dnsTransport,
 	addr *dnscore.ServerAddr,
 	query *dns.Msg,
 ) (*dns.Msg, error) {
 	// If we're not waiting for duplicates, our job is easy
 	if !task.WaitDuplicates {
-		return task.streamResponse(txp.Query(ctx, addr, query))
+		resp, err := txp.Query(ctx, addr, query)
+		return task.streamResponse(addr, resp, err)
 	}
 
 	// Otherwise, we need to reading duplicate responses
 	// until the overall timeout says we should bail, which
-	// happens through context expiration.
+	// happens through context expiration. When configured, the
+	// DuplicatesTimeout bounds how long we collect duplicates.
+	if task.DuplicatesTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, task.DuplicatesTimeout)
+		defer cancel()
+	}
 	var (
 		resp0 *dns.Msg
 		err0  error
@@ -238,7 +665,7 @@ func (task *Task) query(
 	)
 	respch := txp.QueryWithDuplicates(ctx, addr, query)
 	for entry := range respch {
-		resp, err := task.streamResponse(entry.Msg, entry.Err)
+		resp, err := task.streamResponse(addr, entry.Msg, entry.Err)
 		once.Do(func() {
 			resp0, err0 = resp, err
 		})
@@ -249,15 +676,36 @@ func (task *Task) query(
 	return resp0, err0
 }
 
-// streamResponse contains common code to immediately stream a response.
-func (task *Task) streamResponse(resp *dns.Msg, err error) (*dns.Msg, error) {
+// streamResponse contains common code to immediately stream
+// a response received from the server with the given address.
+func (task *Task) streamResponse(addr *dnscore.ServerAddr, resp *dns.Msg, err error) (*dns.Msg, error) {
 	if resp != nil && err == nil {
 		fmt.Fprintf(task.ResponseWriter, "\n;; Response:\n%s\n\n", resp.String())
-		fmt.Fprintf(task.ShortWriter, "%s", task.formatShort(resp))
+		if !task.NoRecursion && !resp.RecursionAvailable {
+			fmt.Fprintf(task.ResponseWriter, ";; WARNING: recursion requested but not available\n\n")
+		}
+		if timeout, ok := responseTCPKeepalive(resp); ok && task.TCPKeepalive {
+			fmt.Fprintf(task.ResponseWriter, ";; TCP keepalive timeout: %s\n\n", timeout)
+		}
+		if subnet, ok := responseClientSubnet(resp); ok && task.ClientSubnet.IsValid() {
+			fmt.Fprintf(task.ResponseWriter, ";; Client subnet scope: /%d (source %s)\n\n",
+				subnet.SourceScope, task.ClientSubnet)
+		}
+		fmt.Fprintf(task.ShortWriter, "%s", task.formatResponse(addr, resp))
 	}
 	return resp, err
 }
 
+// formatAnswers returns the answer RRs of the DNS response in presentation
+// format, omitting the header and all the other sections.
+func formatAnswers(response *dns.Msg) string {
+	var builder strings.Builder
+	for _, ans := range response.Answer {
+		fmt.Fprintf(&builder, "%s\n", ans.String())
+	}
+	return builder.String()
+}
+
 // formatShort returns a short string representation of the DNS response.
 func (task *Task) formatShort(response *dns.Msg) string {
 	var builder strings.Builder
@@ -270,24 +718,47 @@ func (task *Task) formatShort(response *dns.Msg) string {
 			fmt.Fprintf(&builder, "%s\n", ans.AAAA.String())
 
 		case *dns.CNAME:
-			if !task.ShortIP {
+			if task.OutputFormat != outputFormatShortIP {
 				fmt.Fprintf(&builder, "%s\n", ans.Target)
 			}
 
+		case *dns.HINFO:
+			if task.OutputFormat != outputFormatShortIP {
+				fmt.Fprintf(&builder, "%q %q\n", ans.Cpu, ans.Os)
+			}
+
 		case *dns.HTTPS:
-			if !task.ShortIP {
+			if task.OutputFormat != outputFormatShortIP {
+				value := strings.TrimPrefix(ans.String(), ans.Hdr.String())
+				fmt.Fprintf(&builder, "%s\n", value)
+			}
+
+		case *dns.LOC:
+			if task.OutputFormat != outputFormatShortIP {
 				value := strings.TrimPrefix(ans.String(), ans.Hdr.String())
 				fmt.Fprintf(&builder, "%s\n", value)
 			}
 
 		case *dns.MX:
-			if !task.ShortIP {
+			if task.OutputFormat != outputFormatShortIP {
+				value := strings.TrimPrefix(ans.String(), ans.Hdr.String())
+				fmt.Fprintf(&builder, "%s\n", value)
+			}
+
+		case *dns.NAPTR:
+			if task.OutputFormat != outputFormatShortIP {
 				value := strings.TrimPrefix(ans.String(), ans.Hdr.String())
 				fmt.Fprintf(&builder, "%s\n", value)
 			}
 
 		case *dns.NS:
-			if !task.ShortIP {
+			if task.OutputFormat != outputFormatShortIP {
+				value := strings.TrimPrefix(ans.String(), ans.Hdr.String())
+				fmt.Fprintf(&builder, "%s\n", value)
+			}
+
+		case *dns.SVCB:
+			if task.OutputFormat != outputFormatShortIP {
 				value := strings.TrimPrefix(ans.String(), ans.Hdr.String())
 				fmt.Fprintf(&builder, "%s\n", value)
 			}
@@ -0,0 +1,435 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// mockTransport is a [dnsTransport] returning canned responses
+// depending on the address of the server we're querying.
+type mockTransport struct {
+	// responses maps a server address to the answers to return.
+	responses map[string][]dns.RR
+
+	// rcodes optionally maps a server address to the RCODE to return.
+	rcodes map[string]int
+
+	// truncateUDP indicates that UDP responses should be truncated.
+	truncateUDP bool
+}
+
+var _ dnsTransport = &mockTransport{}
+
+// Query implements [dnsTransport].
+func (txp *mockTransport) Query(
+	ctx context.Context, addr *dnscore.ServerAddr, query *dns.Msg) (*dns.Msg, error) {
+	answers, ok := txp.responses[addr.Address]
+	if !ok {
+		return nil, errors.New("mocked error")
+	}
+	resp := &dns.Msg{}
+	resp.SetReply(query)
+	resp.Rcode = txp.rcodes[addr.Address]
+	resp.Answer = answers
+	if txp.truncateUDP && addr.Protocol == dnscore.ProtocolUDP {
+		resp.Truncated = true
+		resp.Answer = nil
+	}
+	return resp, nil
+}
+
+// QueryWithDuplicates implements [dnsTransport].
+func (txp *mockTransport) QueryWithDuplicates(
+	ctx context.Context, addr *dnscore.ServerAddr, query *dns.Msg) <-chan *dnscore.MessageOrError {
+	ch := make(chan *dnscore.MessageOrError, 1)
+	resp, err := txp.Query(ctx, addr, query)
+	ch <- &dnscore.MessageOrError{Msg: resp, Err: err}
+	close(ch)
+	return ch
+}
+
+// newTestTask returns a [*Task] suitable for testing.
+func newTestTask() *Task {
+	return &Task{
+		DiffWriter:     io.Discard,
+		HexDumpWriter:  io.Discard,
+		LogsWriter:     io.Discard,
+		Name:           "www.example.com",
+		Protocol:       "udp",
+		QueryIDWriter:  io.Discard,
+		QueryType:      "A",
+		QueryWriter:    io.Discard,
+		ResponseWriter: io.Discard,
+		ShortWriter:    io.Discard,
+		ServerAddr:     "8.8.8.8",
+		ServerPort:     "53",
+		URLPath:        "/dns-query",
+	}
+}
+
+// newTestServerAddr returns the [*dnscore.ServerAddr] of the server
+// configured by newTestTask, for testing code receiving responses.
+func newTestServerAddr() *dnscore.ServerAddr {
+	return dnscore.NewServerAddr(dnscore.ProtocolUDP, "8.8.8.8:53")
+}
+
+// newTestLogger returns a JSON [*slog.Logger] writing to w.
+func newTestLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{}))
+}
+
+// newTestQuery returns a new query for www.example.com.
+func newTestQuery(t *testing.T) *dns.Msg {
+	query, err := dnscore.NewQuery("www.example.com", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return query
+}
+
+// newTestA returns a new A record for www.example.com.
+func newTestA(addr string) dns.RR {
+	return &dns.A{
+		Hdr: dns.RR_Header{
+			Name:   "www.example.com.",
+			Rrtype: dns.TypeA,
+			Class:  dns.ClassINET,
+			Ttl:    300,
+		},
+		A: net.ParseIP(addr),
+	}
+}
+
+func TestTaskNewServerAddr(t *testing.T) {
+	t.Run("with the default DoH path", func(t *testing.T) {
+		task := newTestTask()
+		task.ServerPort = "443"
+		got := task.newServerAddr(dnscore.ProtocolDoH, "dns.google")
+		if got != "https://dns.google:443/dns-query" {
+			t.Fatalf("unexpected URL: %s", got)
+		}
+	})
+
+	t.Run("with a custom DoH path", func(t *testing.T) {
+		task := newTestTask()
+		task.ServerPort = "443"
+		task.URLPath = "/resolve/custom"
+		got := task.newServerAddr(dnscore.ProtocolDoH, "dns.google")
+		if got != "https://dns.google:443/resolve/custom" {
+			t.Fatalf("unexpected URL: %s", got)
+		}
+	})
+}
+
+func TestTaskExchange(t *testing.T) {
+	txp := &mockTransport{
+		responses: map[string][]dns.RR{
+			"8.8.8.8:53": {newTestA("93.184.216.34")},
+		},
+		truncateUDP: true,
+	}
+
+	t.Run("we retry using TCP with TCPFallback", func(t *testing.T) {
+		var out strings.Builder
+		task := newTestTask()
+		task.ResponseWriter = &out
+		task.TCPFallback = true
+		resp, err := task.exchange(context.Background(), txp, dnscore.ProtocolUDP, "8.8.8.8", newTestQuery(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Truncated || len(resp.Answer) != 1 {
+			t.Fatalf("expected full response, got %v", resp)
+		}
+		if strings.Count(out.String(), ";; Truncated, retrying in TCP mode.\n") != 1 {
+			t.Fatalf("unexpected output: %q", out.String())
+		}
+	})
+
+	t.Run("we do not retry by default", func(t *testing.T) {
+		var out strings.Builder
+		task := newTestTask()
+		task.ResponseWriter = &out
+		resp, err := task.exchange(context.Background(), txp, dnscore.ProtocolUDP, "8.8.8.8", newTestQuery(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Truncated || len(resp.Answer) != 0 {
+			t.Fatalf("expected truncated response, got %v", resp)
+		}
+		if strings.Contains(out.String(), ";; Truncated") {
+			t.Fatalf("unexpected output: %q", out.String())
+		}
+	})
+}
+
+func TestTaskExchangeInOrder(t *testing.T) {
+	txp := &mockTransport{
+		responses: map[string][]dns.RR{
+			"9.9.9.9:53": {newTestA("93.184.216.34")},
+			"8.8.8.8:53": {newTestA("93.184.216.35")},
+		},
+	}
+
+	t.Run("we query ServerAddr when Servers is empty", func(t *testing.T) {
+		task := newTestTask()
+		resp, err := task.exchangeInOrder(context.Background(), txp, dnscore.ProtocolUDP, newTestQuery(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Answer[0].(*dns.A).A.String(); got != "93.184.216.35" {
+			t.Fatalf("unexpected answer: %s", got)
+		}
+	})
+
+	t.Run("we move on to the next server on failure", func(t *testing.T) {
+		task := newTestTask()
+		task.Servers = []string{"1.1.1.1", "9.9.9.9", "8.8.8.8"}
+		resp, err := task.exchangeInOrder(context.Background(), txp, dnscore.ProtocolUDP, newTestQuery(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Answer[0].(*dns.A).A.String(); got != "93.184.216.34" {
+			t.Fatalf("unexpected answer: %s", got)
+		}
+	})
+
+	t.Run("we return all the errors when all servers fail", func(t *testing.T) {
+		task := newTestTask()
+		task.Servers = []string{"1.1.1.1", "1.0.0.1"}
+		_, err := task.exchangeInOrder(context.Background(), txp, dnscore.ProtocolUDP, newTestQuery(t))
+		if err == nil || !strings.Contains(err.Error(), "1.1.1.1") || !strings.Contains(err.Error(), "1.0.0.1") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestTaskStreamResponse(t *testing.T) {
+	newResponse := func(ra bool) *dns.Msg {
+		resp := &dns.Msg{}
+		resp.SetReply(newTestQuery(t))
+		resp.RecursionAvailable = ra
+		return resp
+	}
+	const warning = ";; WARNING: recursion requested but not available"
+
+	t.Run("we warn if recursion is not available", func(t *testing.T) {
+		var out strings.Builder
+		task := newTestTask()
+		task.ResponseWriter = &out
+		task.streamResponse(newTestServerAddr(), newResponse(false), nil)
+		if !strings.Contains(out.String(), warning) {
+			t.Fatalf("expected warning, got %q", out.String())
+		}
+	})
+
+	t.Run("we do not warn if recursion is available", func(t *testing.T) {
+		var out strings.Builder
+		task := newTestTask()
+		task.ResponseWriter = &out
+		task.streamResponse(newTestServerAddr(), newResponse(true), nil)
+		if strings.Contains(out.String(), warning) {
+			t.Fatalf("unexpected warning in %q", out.String())
+		}
+	})
+
+	t.Run("we do not warn without recursion", func(t *testing.T) {
+		var out strings.Builder
+		task := newTestTask()
+		task.NoRecursion = true
+		task.ResponseWriter = &out
+		task.streamResponse(newTestServerAddr(), newResponse(false), nil)
+		if strings.Contains(out.String(), warning) {
+			t.Fatalf("unexpected warning in %q", out.String())
+		}
+	})
+}
+
+func TestTaskFormatShort(t *testing.T) {
+	hdr := func(rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{Name: "www.example.com.", Rrtype: rrtype, Class: dns.ClassINET, Ttl: 300}
+	}
+	resp := &dns.Msg{}
+	resp.Answer = []dns.RR{
+		&dns.HINFO{Hdr: hdr(dns.TypeHINFO), Cpu: "INTEL-386", Os: "Linux 2.0"},
+		&dns.LOC{
+			Hdr:       hdr(dns.TypeLOC),
+			Size:      0x12,                   // 1m
+			HorizPre:  0x16,                   // 10000m
+			VertPre:   0x13,                   // 10m
+			Latitude:  2147483648 + 151200000, // 42 N
+			Longitude: 2147483648 - 259200000, // 72 W
+			Altitude:  10000000 + 1000,        // 10m
+		},
+		&dns.NAPTR{
+			Hdr:         hdr(dns.TypeNAPTR),
+			Order:       100,
+			Preference:  10,
+			Flags:       "u",
+			Service:     "E2U+sip",
+			Regexp:      "!^.*$!sip:info@example.com!",
+			Replacement: ".",
+		},
+		&dns.SVCB{
+			Hdr:      hdr(dns.TypeSVCB),
+			Priority: 0,
+			Target:   "svc.example.net.",
+		},
+		&dns.SVCB{
+			Hdr:      hdr(dns.TypeSVCB),
+			Priority: 1,
+			Target:   ".",
+			Value:    []dns.SVCBKeyValue{&dns.SVCBPort{Port: 8443}},
+		},
+		newTestA("93.184.216.34"),
+	}
+
+	t.Run("with all the answers", func(t *testing.T) {
+		task := newTestTask()
+		expect := strings.Join([]string{
+			`"INTEL-386" "Linux 2.0"`,
+			"42 00 0.000 N 72 00 0.000 W 10m 1m 10000m 10m",
+			`100 10 "u" "E2U+sip" "!^.*$!sip:info@example.com!" .`,
+			"0 svc.example.net.",
+			`1 . port="8443"`,
+			"93.184.216.34",
+			"",
+		}, "\n")
+		if got := task.formatShort(resp); got != expect {
+			t.Fatalf("expected %q, got %q", expect, got)
+		}
+	})
+
+	t.Run("with only the IP addresses", func(t *testing.T) {
+		task := newTestTask()
+		task.OutputFormat = outputFormatShortIP
+		if got := task.formatShort(resp); got != "93.184.216.34\n" {
+			t.Fatalf("unexpected output: %q", got)
+		}
+	})
+}
+
+func TestFormatAnswers(t *testing.T) {
+	resp := &dns.Msg{}
+	resp.SetReply(newTestQuery(t))
+	resp.Answer = []dns.RR{newTestA("93.184.216.34"), newTestA("93.184.216.35")}
+	resp.Ns = []dns.RR{&dns.NS{
+		Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 3600},
+		Ns:  "a.iana-servers.net.",
+	}}
+	resp.Extra = []dns.RR{&dns.A{
+		Hdr: dns.RR_Header{Name: "a.iana-servers.net.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 3600},
+		A:   net.ParseIP("199.43.135.53"),
+	}}
+	expect := strings.Join([]string{
+		"www.example.com.\t300\tIN\tA\t93.184.216.34",
+		"www.example.com.\t300\tIN\tA\t93.184.216.35",
+		"",
+	}, "\n")
+	if got := formatAnswers(resp); got != expect {
+		t.Fatalf("expected %q, got %q", expect, got)
+	}
+}
+
+// duplicatingTransport is a [dnsTransport] returning the given number
+// of responses when waiting for duplicates and then blocking until the
+// context is done, like it happens when waiting for duplicates.
+type duplicatingTransport struct {
+	mockTransport
+	count int
+}
+
+// QueryWithDuplicates implements [dnsTransport].
+func (txp *duplicatingTransport) QueryWithDuplicates(
+	ctx context.Context, addr *dnscore.ServerAddr, query *dns.Msg) <-chan *dnscore.MessageOrError {
+	ch := make(chan *dnscore.MessageOrError, txp.count)
+	for idx := 0; idx < txp.count; idx++ {
+		resp, err := txp.Query(ctx, addr, query)
+		ch <- &dnscore.MessageOrError{Msg: resp, Err: err}
+	}
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
+
+func TestTaskQueryDuplicatesTimeout(t *testing.T) {
+	txp := &duplicatingTransport{
+		mockTransport: mockTransport{
+			responses: map[string][]dns.RR{
+				"8.8.8.8:53": {newTestA("93.184.216.34")},
+			},
+		},
+		count: 2,
+	}
+	var out strings.Builder
+	task := newTestTask()
+	task.DuplicatesTimeout = 10 * time.Millisecond
+	task.ResponseWriter = &out
+	task.WaitDuplicates = true
+	addr := dnscore.NewServerAddr(dnscore.ProtocolUDP, "8.8.8.8:53")
+	t0 := time.Now()
+	resp, err := task.query(context.Background(), txp, addr, newTestQuery(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(t0); elapsed >= time.Second {
+		t.Fatalf("expected to stop collecting duplicates early, took %v", elapsed)
+	}
+	if len(resp.Answer) != 1 {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+	if count := strings.Count(out.String(), ";; Response:"); count != 2 {
+		t.Fatalf("expected 2 responses, got %d", count)
+	}
+}
+
+func TestTaskCheckResponseNoValidate(t *testing.T) {
+	// Create a response whose ID does not match the query ID
+	txp := &mockTransport{
+		responses: map[string][]dns.RR{
+			"8.8.8.8:53": {newTestA("93.184.216.34")},
+		},
+		rcodes: map[string]int{
+			"8.8.8.8:53": dns.RcodeServerFailure,
+		},
+	}
+	query := newTestQuery(t)
+	var out strings.Builder
+	task := newTestTask()
+	task.ResponseWriter = &out
+	resp, err := task.exchangeInOrder(context.Background(), txp, dnscore.ProtocolUDP, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Id = query.Id + 1
+	if !strings.Contains(out.String(), "93.184.216.34") {
+		t.Fatalf("expected the response to be printed, got %q", out.String())
+	}
+	logger := newTestLogger(io.Discard)
+
+	t.Run("we fail without NoValidate", func(t *testing.T) {
+		if err := task.checkResponse(context.Background(), logger, query, resp); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("we succeed with NoValidate", func(t *testing.T) {
+		task.NoValidate = true
+		if err := task.checkResponse(context.Background(), logger, query, resp); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
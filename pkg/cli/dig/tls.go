@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"crypto/tls"
+	"net"
+
+	"github.com/rbmk-project/x/netcore"
+)
+
+// newTLSClientConn creates a new TLS client connection after modifying
+// the [*tls.Config] according to the task's TLS settings.
+//
+// We modify the config in place, which is safe because netcore either
+// creates a new config or clones the configured one for each dial, and
+// it is also desirable because netcore logs the config fields.
+func (task *Task) newTLSClientConn(conn net.Conn, config *tls.Config) netcore.TLSConn {
+	if len(task.ALPN) > 0 {
+		config.NextProtos = task.ALPN
+	}
+	if task.Insecure {
+		config.InsecureSkipVerify = true
+	}
+	return tls.Client(conn, config)
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dig
+
+import (
+	"crypto/tls"
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestTaskNewTLSClientConn(t *testing.T) {
+	t.Run("without ALPN we keep the default ALPN", func(t *testing.T) {
+		task := newTestTask()
+		config := &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+		conn, peer := net.Pipe()
+		defer conn.Close()
+		defer peer.Close()
+		task.newTLSClientConn(conn, config)
+		if !slices.Equal(config.NextProtos, []string{"h2", "http/1.1"}) {
+			t.Fatalf("unexpected ALPN: %v", config.NextProtos)
+		}
+	})
+
+	t.Run("with ALPN we override the default ALPN", func(t *testing.T) {
+		task := newTestTask()
+		task.ALPN = []string{"dot"}
+		config := &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+		conn, peer := net.Pipe()
+		defer conn.Close()
+		defer peer.Close()
+		task.newTLSClientConn(conn, config)
+		if !slices.Equal(config.NextProtos, []string{"dot"}) {
+			t.Fatalf("unexpected ALPN: %v", config.NextProtos)
+		}
+	})
+
+	t.Run("by default we verify the certificate", func(t *testing.T) {
+		task := newTestTask()
+		config := &tls.Config{}
+		conn, peer := net.Pipe()
+		defer conn.Close()
+		defer peer.Close()
+		task.newTLSClientConn(conn, config)
+		if config.InsecureSkipVerify {
+			t.Fatal("expected certificate verification")
+		}
+	})
+
+	t.Run("with Insecure we skip verifying the certificate", func(t *testing.T) {
+		task := newTestTask()
+		task.Insecure = true
+		config := &tls.Config{}
+		conn, peer := net.Pipe()
+		defer conn.Close()
+		defer peer.Close()
+		task.newTLSClientConn(conn, config)
+		if !config.InsecureSkipVerify {
+			t.Fatal("expected to skip certificate verification")
+		}
+	})
+}
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package qa
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/rbmk-project/common/cliutils"
+	"github.com/rbmk-project/rbmk/internal/markdown"
+	qacore "github.com/rbmk-project/rbmk/internal/qa"
+)
+
+// newListCommand creates the `rbmk qa list` command.
+func newListCommand() cliutils.Command {
+	return listCommand{}
+}
+
+// listCommand implements [cliutils.Command].
+type listCommand struct{}
+
+var _ cliutils.Command = listCommand{}
+
+//go:embed list.md
+var listDocs string
+
+// Help implements [cliutils.Command].
+func (cmd listCommand) Help(env cliutils.Environment, argv ...string) error {
+	fmt.Fprintf(env.Stdout(), "%s\n", markdown.MaybeRender(listDocs))
+	return nil
+}
+
+// Main implements [cliutils.Command].
+func (cmd listCommand) Main(ctx context.Context, env cliutils.Environment, argv ...string) error {
+	// 1. honour requests for printing the help
+	if cliutils.HelpRequested(argv...) {
+		return cmd.Help(env, argv...)
+	}
+
+	// 2. ensure there are no command line arguments
+	if len(argv) > 1 {
+		err := errors.New("expected no positional arguments")
+		fmt.Fprintf(env.Stderr(), "rbmk qa list: %s\n", err.Error())
+		fmt.Fprintf(env.Stderr(), "Run `rbmk qa list --help` for usage.\n")
+		return err
+	}
+
+	// 3. print the name of each scenario
+	for _, desc := range qacore.Registry {
+		fmt.Fprintln(env.Stdout(), desc.Name)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package qa implements the `rbmk qa` command.
+package qa
+
+import (
+	_ "embed"
+
+	"github.com/rbmk-project/common/cliutils"
+	"github.com/rbmk-project/rbmk/internal/markdown"
+)
+
+//go:embed README.md
+var readme string
+
+// NewCommand creates the `rbmk qa` Command.
+//
+// The newRoot argument is the function creating the `rbmk` command,
+// which we use to run the scenarios. We receive it as an argument because
+// the package implementing the `rbmk` command includes this command.
+func NewCommand(newRoot func() cliutils.Command) cliutils.Command {
+	return cliutils.NewCommandWithSubCommands(
+		"qa", markdown.LazyMaybeRender(readme),
+		map[string]cliutils.Command{
+			"list": newListCommand(),
+			"run":  newRunCommand(newRoot),
+		})
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package qa_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/rbmk-project/rbmk/internal/testable"
+	"github.com/rbmk-project/rbmk/pkg/cli"
+	"github.com/rbmk-project/rbmk/pkg/cli/qa"
+)
+
+func TestCommand(t *testing.T) {
+	// newEnv returns an environment writing stdout into the given buffer
+	newEnv := func(stdout *bytes.Buffer) *testable.Environment {
+		env := testable.NewEnvironment()
+		env.SetStdout(stdout)
+		env.SetStderr(&bytes.Buffer{})
+		return env
+	}
+	cmd := qa.NewCommand(cli.NewCommand)
+
+	t.Run("list prints the known scenarios", func(t *testing.T) {
+		var stdout bytes.Buffer
+		if err := cmd.Main(context.Background(), newEnv(&stdout), "qa", "list"); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"dnsOverUdpSuccess", "dnsOverTcpSuccess"} {
+			if !strings.Contains(stdout.String(), name+"\n") {
+				t.Fatalf("missing %s in %q", name, stdout.String())
+			}
+		}
+	})
+
+	t.Run("run executes the chosen scenario", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("skip test in short mode")
+		}
+		var stdout bytes.Buffer
+		if err := cmd.Main(context.Background(), newEnv(&stdout), "qa", "run", "dnsOverUdpSuccess"); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(stdout.String(), "PASS dnsOverUdpSuccess") ||
+			!strings.Contains(stdout.String(), "1 scenarios, 1 passed, 0 failed") {
+			t.Fatalf("unexpected report: %q", stdout.String())
+		}
+	})
+
+	t.Run("run --verbose writes the simulation logs to the stderr", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("skip test in short mode")
+		}
+		var stdout, stderr bytes.Buffer
+		env := newEnv(&stdout)
+		env.SetStderr(&stderr)
+		output := log.Writer()
+		argv := []string{"qa", "run", "--verbose", "dnsOverUdpSuccess"}
+		if err := cmd.Main(context.Background(), env, argv...); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(stderr.String(), "OPEN ") {
+			t.Fatalf("missing simulation logs in %q", stderr.String())
+		}
+		if log.Writer() != output {
+			t.Fatal("expected the standard library logger output to be restored")
+		}
+	})
+
+	t.Run("run fails with an unknown scenario", func(t *testing.T) {
+		var stdout bytes.Buffer
+		err := cmd.Main(context.Background(), newEnv(&stdout), "qa", "run", "nonexistent")
+		if err == nil || err.Error() != "unknown scenario: nonexistent" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package qa
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rbmk-project/common/cliutils"
+	"github.com/rbmk-project/rbmk/internal/markdown"
+	qacore "github.com/rbmk-project/rbmk/internal/qa"
+	"github.com/spf13/pflag"
+)
+
+// newRunCommand creates the `rbmk qa run` command.
+func newRunCommand(newRoot func() cliutils.Command) cliutils.Command {
+	return runCommand{newRoot: newRoot}
+}
+
+// runCommand implements [cliutils.Command].
+type runCommand struct {
+	newRoot func() cliutils.Command
+}
+
+var _ cliutils.Command = runCommand{}
+
+//go:embed run.md
+var runDocs string
+
+// Help implements [cliutils.Command].
+func (cmd runCommand) Help(env cliutils.Environment, argv ...string) error {
+	fmt.Fprintf(env.Stdout(), "%s\n", markdown.MaybeRender(runDocs))
+	return nil
+}
+
+// Main implements [cliutils.Command].
+func (cmd runCommand) Main(ctx context.Context, env cliutils.Environment, argv ...string) error {
+	// 1. honour requests for printing the help
+	if cliutils.HelpRequested(argv...) {
+		return cmd.Help(env, argv...)
+	}
+
+	// 2. parse command line
+	clip := pflag.NewFlagSet("rbmk qa run", pflag.ContinueOnError)
+	verbose := clip.BoolP("verbose", "v", false, "write the scenarios logs to the stderr")
+
+	if err := clip.Parse(argv[1:]); err != nil {
+		fmt.Fprintf(env.Stderr(), "rbmk qa run: %s\n", err.Error())
+		fmt.Fprintf(env.Stderr(), "Run `rbmk qa run --help` for usage.\n")
+		return err
+	}
+
+	// 3. select the scenarios to run, defaulting to all of them
+	scenarios, err := selectScenarios(clip.Args()...)
+	if err != nil {
+		fmt.Fprintf(env.Stderr(), "rbmk qa run: %s\n", err.Error())
+		fmt.Fprintf(env.Stderr(), "Run `rbmk qa list` for the available scenarios.\n")
+		return err
+	}
+
+	// 4. create a temporary directory for caching the TLS certificates
+	cacheDir, err := os.MkdirTemp("", "rbmk-qa-")
+	if err != nil {
+		err = fmt.Errorf("cannot create cache directory: %w", err)
+		fmt.Fprintf(env.Stderr(), "rbmk qa run: %s\n", err.Error())
+		return err
+	}
+	defer os.RemoveAll(cacheDir)
+
+	// 5. run each scenario using the report driver, writing the
+	// scenarios logs to the stderr only when --verbose is set
+	var logs io.Writer = io.Discard
+	if *verbose {
+		logs = env.Stderr()
+	}
+	driver := qacore.NewReportDriver(logs)
+	config := &qacore.RunConfig{CacheDir: cacheDir, NewCommand: cmd.newRoot, Logs: logs}
+	for _, desc := range scenarios {
+		driver.Run(desc.Name, func(t qacore.Driver) {
+			desc.VerifyEvents(t, desc.Run(t, config))
+		})
+	}
+
+	// 6. write the report and fail if any scenario failed
+	driver.WriteReport(env.Stdout())
+	if driver.Failed() {
+		err := errors.New("some QA scenarios failed")
+		fmt.Fprintf(env.Stderr(), "rbmk qa run: %s\n", err.Error())
+		return err
+	}
+	return nil
+}
+
+// selectScenarios returns the scenarios with the given names in the
+// given order or all the scenarios when no names are provided.
+func selectScenarios(names ...string) ([]qacore.ScenarioDescriptor, error) {
+	if len(names) <= 0 {
+		return qacore.Registry, nil
+	}
+	var scenarios []qacore.ScenarioDescriptor
+	for _, name := range names {
+		desc, found := findScenario(name)
+		if !found {
+			return nil, fmt.Errorf("unknown scenario: %s", name)
+		}
+		scenarios = append(scenarios, desc)
+	}
+	return scenarios, nil
+}
+
+// findScenario returns the scenario with the given name, if any.
+func findScenario(name string) (qacore.ScenarioDescriptor, bool) {
+	for _, desc := range qacore.Registry {
+		if desc.Name == name {
+			return desc, true
+		}
+	}
+	return qacore.ScenarioDescriptor{}, false
+}